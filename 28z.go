@@ -5,31 +5,105 @@ import (
 	"dmccaffrey/28z/ui"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 )
 
+// Find the ROM directory named by Z28_ROM, or else the one beside the executable, so that
+// scripts run from any directory, falling back to rom/ in the working directory
+func defaultRom() string {
+	if rom := os.Getenv("Z28_ROM"); rom != "" {
+		return rom
+	}
+	if executable, err := os.Executable(); err == nil {
+		if executable, err = filepath.EvalSymlinks(executable); err == nil {
+			rom := filepath.Join(filepath.Dir(executable), "rom")
+			if info, err := os.Stat(rom); err == nil && info.IsDir() {
+				return rom
+			}
+		}
+	}
+	return "rom/"
+}
+
 func main() {
-	//enableDebug := flag.Bool("debug", false, "Enable debug output")
 	help := flag.Bool("help", false, "Output help documentation")
 	eval := flag.String("eval", "", "Specify a reference to evaluate on start")
+	run := flag.String("run", "", "Run a program without the interactive UI; remaining args are pushed onto the stack")
+	rom := flag.String("rom", defaultRom(), "Specify the ROM directory, which defaults to $Z28_ROM or the rom directory beside the executable")
+	test := flag.String("test", "", "Run the test programs in a directory and report the results")
+	bench := flag.String("bench", "", "Time a program, or the programs in a directory, evaluated directly and compiled")
+	benchTime := flag.Duration("benchtime", time.Second, "Time spent evaluating each program with -bench")
+	restore := flag.String("restore", "", "Restore the VM state from a file on start")
+	save := flag.String("save", "", "Save the VM state to a file on exit")
+	snapshots := flag.String("snapshots", "", "Specify the directory the save and restore instructions are confined to, which defaults to the working directory")
+	debug := flag.Bool("debug", false, "Start the debugger with -run, reading commands from stdin")
+	quotas := core.DefaultQuotas
+	flag.IntVar(&quotas.MaxInstructions, "maxinstructions", 0, "Limit the values evaluated for each input, or 0 for no limit")
+	flag.IntVar(&quotas.MaxStackEntries, "maxstack", 0, "Limit the entries on each stack, or 0 for no limit")
+	flag.IntVar(&quotas.MaxStackDepth, "maxdepth", 0, "Limit the number of nested stacks, or 0 for no limit")
+	flag.IntVar(&quotas.MaxValueBytes, "maxbytes", 0, "Limit the bytes held by each value, or 0 for no limit")
+	flag.IntVar(&quotas.MaxVariables, "maxvars", 0, "Limit the number of variables, or 0 for no limit")
+	flag.IntVar(&quotas.MaxRepeat, "maxrepeat", core.DefaultQuotas.MaxRepeat, "Limit the iterations of repeat, or 0 for no limit")
+	flag.DurationVar(&quotas.Timeout, "timeout", 0, "Limit the time taken by each input, such as 5s, or 0 for no limit")
+	flag.IntVar(&quotas.MaxFrames, "maxframes", 0, fmt.Sprintf("Limit the nested frames of evaluation, or 0 for %d", core.DefaultMaxFrames))
 	flag.Parse()
 	if *help {
 		OutputHelpDocumentation()
 		return
 	}
 
-	core.LogToFile()
+	// Benchmarks leave logging off, to time evaluation rather than writing the log
+	if *bench == "" {
+		core.LogToFile()
+	}
 
 	core.Logger.Printf("Initializing ROM\n")
-	err := core.LoadRom()
+	r0, err := core.LoadRom(*rom)
 	if err != nil {
-		fmt.Printf("Failed to load ROM: %s\n", err.Error())
+		fmt.Fprintf(os.Stderr, "Failed to load ROM: %s\n", err.Error())
+		os.Exit(2)
+	}
+	for _, d := range r0.Diagnostics {
+		fmt.Fprintf(os.Stderr, "ROM error: %s\n", d.Error())
+	}
+
+	if *bench != "" {
+		if ui.NewBenchRunner(r0, *bench, *benchTime).Run() != 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *test != "" {
+		core.Logger.Printf("Running tests: dir=%s\n", *test)
+		if ui.NewTestRunner(r0, *test).Run() != 0 {
+			os.Exit(1)
+		}
 		return
 	}
 
-	core.Logger.Printf("Initializing instruction map\n")
-	core.InitializeInstructionMap()
+	if *run != "" {
+		core.Logger.Printf("Running headless: program=%s, args=%s\n", *run, flag.Args())
+		vm := core.NewVM(r0)
+		vm.SetQuotas(quotas)
+		vm.SetSnapshotDir(*snapshots)
+		restoreState(vm.Core(), *restore)
+		z := ui.NewHeadless28z(vm)
+		if *debug {
+			z.EnableDebugger()
+		}
+		code := z.Run(*run, flag.Args())
+		saveState(vm.Core(), *save)
+		os.Exit(code)
+	}
+
+	c0 := core.NewCore(r0)
+	c0.Quotas = quotas
+	c0.SnapshotDir = *snapshots
+	restoreState(c0, *restore)
 
-	c0 := core.NewCore()
 	z := ui.NewInteractive28z(c0)
 	core.Logger.Printf("Initializing core\n")
 	if *eval != "" {
@@ -38,6 +112,30 @@ func main() {
 	}
 	core.Logger.Printf("Starting main loop\n")
 	z.Run()
+	saveState(c0, *save)
+}
+
+func restoreState(c *core.Core, path string) {
+	if path == "" {
+		return
+	}
+	core.Logger.Printf("Restoring state: path=%s\n", path)
+	err := c.Restore(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to restore state: %s\n", err.Error())
+		os.Exit(2)
+	}
+}
+
+func saveState(c *core.Core, path string) {
+	if path == "" {
+		return
+	}
+	core.Logger.Printf("Saving state: path=%s\n", path)
+	err := c.Save(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save state: %s\n", err.Error())
+	}
 }
 
 func OutputHelpDocumentation() {
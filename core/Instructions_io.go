@@ -14,25 +14,26 @@ func print(core *Core) InstructionResult {
 	if x.GetType() == ReferenceType {
 		x = x.(ReferenceValue).Dereference(core)
 	}
-	core.Control <- CommandMessage{Command: Output, Arg: x.GetString()}
-	core.Control <- CommandMessage{Command: StateUpdated, Arg: ""}
+	core.Emit(Output, core.Format(x))
+	core.Emit(StateUpdated, "")
 	return successResult
 }
 
 func clearBuffer(core *Core) InstructionResult {
-	core.Control <- CommandMessage{Command: Clear, Arg: ""}
+	core.Emit(Clear, "")
 	return successResult
 }
 
 func render(core *Core) InstructionResult {
-	core.Control <- CommandMessage{Command: Clear, Arg: ""}
+	core.Emit(Clear, "")
+	symbols := core.env.rom.Symbols
 	for r := 0; r < 30; r++ {
 		var sb strings.Builder
 		for c := 0; c < 92; c++ {
 			value := int(core.Ram[92*r+c])
-			if value >= 128 {
-				value = (value & 127) % len(Symbols)
-				sb.WriteRune(Symbols[value])
+			if value >= 128 && len(symbols) > 0 {
+				value = (value & 127) % len(symbols)
+				sb.WriteRune(symbols[value])
 
 			} else {
 				if value < 32 {
@@ -41,14 +42,14 @@ func render(core *Core) InstructionResult {
 				sb.WriteRune(rune(value))
 			}
 		}
-		core.Control <- CommandMessage{Command: Output, Arg: sb.String()}
+		core.Emit(Output, sb.String())
 	}
 	return successResult
 }
 
 func show(core *Core) InstructionResult {
 	render(core)
-	core.Control <- CommandMessage{Command: StateUpdated, Arg: ""}
+	core.Emit(StateUpdated, "")
 
 	return successResult
 }
@@ -62,6 +63,9 @@ func sleep(core *Core) InstructionResult {
 func graph(core *Core) InstructionResult {
 	f := consumeOne(core)
 	end, start := consumeTwo(core)
+	if algebraic, ok := f.(AlgebraicValue); ok {
+		f = toRPN(core, algebraic)
+	}
 
 	y := -1
 	step := (end.GetFloat() - start.GetFloat()) / 92
@@ -88,7 +92,7 @@ func graph(core *Core) InstructionResult {
 	for col := 0; col < 92; col++ {
 		x := float64(col) * step
 		core.Push(FloatValue{value: float64(x)})
-		core.EvalSequence(f.GetSequence())
+		core.evalBody(f)
 		result := consumeOne(core)
 		results[col] = result.GetFloat()
 	}
@@ -134,7 +138,7 @@ func xyToOffset(x int, y int) int {
 
 func prompt(core *Core) InstructionResult {
 	x := consumeOne(core)
-	core.Control <- CommandMessage{Command: Prompt, Arg: x.GetString()}
+	core.Emit(Prompt, x.GetString())
 	return successResult
 }
 
@@ -144,3 +148,20 @@ func inspect(core *Core) InstructionResult {
 	os.WriteFile("inspect", []byte(output), 0644)
 	return successResult
 }
+
+// Choose how floats are shown, taking the number of digits from the stack except in std mode
+func setDisplay(mode DisplayMode) InstructionImpl {
+	return func(core *Core) InstructionResult {
+		digits := 0
+		if mode != StdDisplay {
+			x := consumeOne(core)
+			if !isNumeric(x) || x.GetInt() < 0 || x.GetInt() > 15 {
+				return InstructionResult{true, "Expected between 0 and 15 digits"}
+			}
+			digits = x.GetInt()
+		}
+		core.Regs.Display = mode
+		core.Regs.Digits = digits
+		return successResult
+	}
+}
@@ -0,0 +1,646 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A node of an algebraic expression: a number, a variable, an operator applied to
+// its operands, or a function applied to a single operand
+type expr struct {
+	op       string
+	value    float64
+	name     string
+	operands []*expr
+}
+
+var algebraicFunctions = map[string]func(float64) float64{
+	"sin":  math.Sin,
+	"cos":  math.Cos,
+	"exp":  math.Exp,
+	"ln":   math.Log,
+	"sqrt": math.Sqrt,
+}
+
+func number(value float64) *expr {
+	return &expr{op: "num", value: value}
+}
+
+func variable(name string) *expr {
+	return &expr{op: "var", name: name}
+}
+
+func binary(op string, y *expr, x *expr) *expr {
+	return &expr{op: op, operands: []*expr{y, x}}
+}
+
+func call(name string, x *expr) *expr {
+	return &expr{op: "fn", name: name, operands: []*expr{x}}
+}
+
+func (e *expr) isNumber(value float64) bool {
+	return e.op == "num" && e.value == value
+}
+
+// Check if the expression divides by a divisor that is, or may be, zero
+func (e *expr) mayDivideByZero() bool {
+	if e.op == "/" && (e.operands[1].op != "num" || e.operands[1].value == 0) {
+		return true
+	}
+	for _, operand := range e.operands {
+		if operand.mayDivideByZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse an expression such as x^2+3*x
+func parseAlgebraic(input string) (*expr, error) {
+	p := algebraicParser{input: input}
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("unexpected input at %d: %s", p.pos, p.input[p.pos:])
+	}
+	return e, nil
+}
+
+type algebraicParser struct {
+	input string
+	pos   int
+}
+
+func (p *algebraicParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *algebraicParser) accept(op byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *algebraicParser) sum() (*expr, error) {
+	e, err := p.product()
+	for err == nil {
+		var op string
+		if p.accept('+') {
+			op = "+"
+		} else if p.accept('-') {
+			op = "-"
+		} else {
+			break
+		}
+		var operand *expr
+		operand, err = p.product()
+		e = binary(op, e, operand)
+	}
+	return e, err
+}
+
+func (p *algebraicParser) product() (*expr, error) {
+	e, err := p.unary()
+	for err == nil {
+		var op string
+		if p.accept('*') {
+			op = "*"
+		} else if p.accept('/') {
+			op = "/"
+		} else {
+			break
+		}
+		var operand *expr
+		operand, err = p.unary()
+		e = binary(op, e, operand)
+	}
+	return e, err
+}
+
+func (p *algebraicParser) unary() (*expr, error) {
+	if p.accept('-') {
+		e, err := p.unary()
+		return &expr{op: "neg", operands: []*expr{e}}, err
+	}
+	return p.power()
+}
+
+func (p *algebraicParser) power() (*expr, error) {
+	e, err := p.atom()
+	if err == nil && p.accept('^') {
+		var exponent *expr
+		exponent, err = p.unary()
+		e = binary("^", e, exponent)
+	}
+	return e, err
+}
+
+func (p *algebraicParser) atom() (*expr, error) {
+	p.skipSpace()
+	if p.accept('(') {
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+	}
+	start := p.pos
+	if p.pos < len(p.input) && (unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '.') {
+		for p.pos < len(p.input) && (unicode.IsDigit(rune(p.input[p.pos])) || strings.ContainsRune(".eE", rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", p.input[start:p.pos])
+		}
+		return number(value), nil
+	}
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+	if name == "" {
+		return nil, fmt.Errorf("expected a number or name at %d", start)
+	}
+	if _, ok := algebraicFunctions[name]; ok && p.accept('(') {
+		operand, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return call(name, operand), nil
+	}
+	return variable(name), nil
+}
+
+func precedence(e *expr) int {
+	switch e.op {
+	case "+", "-":
+		return 1
+	case "*", "/":
+		return 2
+	case "neg":
+		return 3
+	case "^":
+		return 4
+	case "num":
+		if e.value < 0 {
+			return 3
+		}
+	}
+	return 5
+}
+
+func (e *expr) String() string {
+	switch e.op {
+	case "num":
+		return strconv.FormatFloat(e.value, 'g', -1, 64)
+	case "var":
+		return e.name
+	case "fn":
+		return e.name + "(" + e.operands[0].String() + ")"
+	case "neg":
+		return "-" + e.operand(0, 3, false)
+	}
+	p := precedence(e)
+	if e.op == "^" {
+		return e.operand(0, p, true) + e.op + e.operand(1, p, false)
+	}
+	return e.operand(0, p, false) + e.op + e.operand(1, p, e.op == "-" || e.op == "/")
+}
+
+// Describe an operand, in parentheses if it binds less tightly than its parent
+func (e *expr) operand(i int, parent int, strict bool) string {
+	child := e.operands[i]
+	p := precedence(child)
+	if p < parent || (strict && p == parent) {
+		return "(" + child.String() + ")"
+	}
+	return child.String()
+}
+
+// Collect the names of the variables in the expression, in order of appearance
+func (e *expr) variables(names []string) []string {
+	if e.op == "var" && !slices.Contains(names, e.name) {
+		return append(names, e.name)
+	}
+	for _, operand := range e.operands {
+		names = operand.variables(names)
+	}
+	return names
+}
+
+// Replace every occurrence of a variable
+func (e *expr) substitute(name string, value *expr) *expr {
+	if e.op == "var" && e.name == name {
+		return value
+	}
+	if len(e.operands) == 0 {
+		return e
+	}
+	result := *e
+	result.operands = make([]*expr, len(e.operands))
+	for i, operand := range e.operands {
+		result.operands[i] = operand.substitute(name, value)
+	}
+	return &result
+}
+
+// Fold constants and remove identities such as x+0 and x*1
+func (e *expr) simplify() *expr {
+	if len(e.operands) == 0 {
+		return e
+	}
+	operands := make([]*expr, len(e.operands))
+	constant := true
+	for i, operand := range e.operands {
+		operands[i] = operand.simplify()
+		constant = constant && operands[i].op == "num"
+	}
+	result := &expr{op: e.op, name: e.name, operands: operands}
+	if constant {
+		if value, ok := result.evaluate(nil); ok && !math.IsNaN(value) && !math.IsInf(value, 0) {
+			return number(value)
+		}
+	}
+	if e.op == "neg" || e.op == "fn" {
+		if e.op == "neg" && operands[0].op == "neg" {
+			return operands[0].operands[0]
+		}
+		return result
+	}
+
+	y, x := operands[0], operands[1]
+	switch e.op {
+	case "+":
+		if y.isNumber(0) {
+			return x
+		}
+		if x.isNumber(0) {
+			return y
+		}
+		if x.op == "neg" {
+			return binary("-", y, x.operands[0]).simplify()
+		}
+	case "-":
+		if x.isNumber(0) {
+			return y
+		}
+		if y.isNumber(0) {
+			return (&expr{op: "neg", operands: []*expr{x}}).simplify()
+		}
+		if x.op == "neg" {
+			return binary("+", y, x.operands[0]).simplify()
+		}
+	case "*":
+		if y.isNumber(0) && !x.mayDivideByZero() || x.isNumber(0) && !y.mayDivideByZero() {
+			return number(0)
+		}
+		if y.isNumber(1) {
+			return x
+		}
+		if x.isNumber(1) {
+			return y
+		}
+		if x.op == "num" && y.op != "num" {
+			return binary("*", x, y)
+		}
+	case "/":
+		if x.isNumber(1) {
+			return y
+		}
+	case "^":
+		if x.isNumber(0) {
+			return number(1)
+		}
+		if x.isNumber(1) {
+			return y
+		}
+	}
+	return result
+}
+
+// Evaluate the expression numerically, looking up variables with lookup
+func (e *expr) evaluate(lookup func(string) (float64, bool)) (float64, bool) {
+	switch e.op {
+	case "num":
+		return e.value, true
+	case "var":
+		if lookup == nil {
+			return 0, false
+		}
+		return lookup(e.name)
+	}
+	values := make([]float64, len(e.operands))
+	for i, operand := range e.operands {
+		value, ok := operand.evaluate(lookup)
+		if !ok {
+			return 0, false
+		}
+		values[i] = value
+	}
+	switch e.op {
+	case "neg":
+		return -values[0], true
+	case "fn":
+		return algebraicFunctions[e.name](values[0]), true
+	case "+":
+		return values[0] + values[1], true
+	case "-":
+		return values[0] - values[1], true
+	case "*":
+		return values[0] * values[1], true
+	case "/":
+		return values[0] / values[1], true
+	case "^":
+		return math.Pow(values[0], values[1]), true
+	}
+	return 0, false
+}
+
+// Differentiate the expression with respect to a variable
+func (e *expr) derivative(name string) *expr {
+	switch e.op {
+	case "num":
+		return number(0)
+	case "var":
+		if e.name == name {
+			return number(1)
+		}
+		return number(0)
+	case "neg":
+		return &expr{op: "neg", operands: []*expr{e.operands[0].derivative(name)}}
+	case "fn":
+		u := e.operands[0]
+		du := u.derivative(name)
+		switch e.name {
+		case "sin":
+			return binary("*", call("cos", u), du)
+		case "cos":
+			return binary("*", &expr{op: "neg", operands: []*expr{call("sin", u)}}, du)
+		case "exp":
+			return binary("*", e, du)
+		case "ln":
+			return binary("/", du, u)
+		case "sqrt":
+			return binary("/", du, binary("*", number(2), e))
+		}
+	}
+	u, v := e.operands[0], e.operands[1]
+	du, dv := u.derivative(name), v.derivative(name)
+	switch e.op {
+	case "+", "-":
+		return binary(e.op, du, dv)
+	case "*":
+		return binary("+", binary("*", du, v), binary("*", u, dv))
+	case "/":
+		return binary("/", binary("-", binary("*", du, v), binary("*", u, dv)), binary("^", v, number(2)))
+	case "^":
+		if !slices.Contains(v.variables(nil), name) {
+			return binary("*", binary("*", v, binary("^", u, binary("-", v, number(1)))), du)
+		}
+		// ln(u) already requires u to be positive, so v/u cancels when v is u
+		term := binary("/", binary("*", v, du), u)
+		if v.String() == u.String() {
+			term = du
+		}
+		return binary("*", e, binary("+", binary("*", dv, call("ln", u)), term))
+	}
+	return number(0)
+}
+
+// Distribute products over sums and expand small integer powers of sums
+func (e *expr) expand() *expr {
+	if len(e.operands) == 0 {
+		return e
+	}
+	result := &expr{op: e.op, name: e.name, operands: make([]*expr, len(e.operands))}
+	for i, operand := range e.operands {
+		result.operands[i] = operand.expand()
+	}
+	switch result.op {
+	case "neg":
+		if terms := result.operands[0]; terms.op == "+" || terms.op == "-" {
+			op := "-"
+			if terms.op == "-" {
+				op = "+"
+			}
+			return binary(op, (&expr{op: "neg", operands: []*expr{terms.operands[0]}}).expand(), terms.operands[1])
+		}
+	case "*":
+		y, x := result.operands[0], result.operands[1]
+		if y.op == "+" || y.op == "-" {
+			return binary(y.op, binary("*", y.operands[0], x).expand(), binary("*", y.operands[1], x).expand())
+		}
+		if x.op == "+" || x.op == "-" {
+			return binary(x.op, binary("*", y, x.operands[0]).expand(), binary("*", y, x.operands[1]).expand())
+		}
+	case "/":
+		y, x := result.operands[0], result.operands[1]
+		if y.op == "+" || y.op == "-" {
+			return binary(y.op, binary("/", y.operands[0], x).expand(), binary("/", y.operands[1], x).expand())
+		}
+	case "^":
+		y, x := result.operands[0], result.operands[1]
+		if (y.op == "+" || y.op == "-") && x.op == "num" && x.value >= 2 && x.value <= 16 && x.value == math.Trunc(x.value) {
+			product := y
+			for i := 1; i < int(x.value); i++ {
+				product = binary("*", product, y).expand()
+			}
+			return product
+		}
+	}
+	return result
+}
+
+type (
+	factor struct {
+		base  *expr
+		power float64
+	}
+	term struct {
+		coefficient float64
+		factors     []factor
+	}
+)
+
+// Combine like terms of a sum and like factors of each product
+func (e *expr) collect() *expr {
+	terms := []term{}
+	for _, t := range e.simplify().terms(1) {
+		key := t.key()
+		found := false
+		for i := range terms {
+			if terms[i].key() == key {
+				terms[i].coefficient += t.coefficient
+				found = true
+				break
+			}
+		}
+		if !found {
+			terms = append(terms, t)
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return terms[i].degree() > terms[j].degree()
+	})
+
+	var result *expr
+	for _, t := range terms {
+		if t.coefficient == 0 {
+			continue
+		}
+		if result == nil {
+			result = t.expr(t.coefficient)
+		} else if t.coefficient < 0 {
+			result = binary("-", result, t.expr(-t.coefficient))
+		} else {
+			result = binary("+", result, t.expr(t.coefficient))
+		}
+	}
+	if result == nil {
+		return number(0)
+	}
+	return result
+}
+
+// Flatten a sum into its terms
+func (e *expr) terms(sign float64) []term {
+	switch e.op {
+	case "+":
+		return append(e.operands[0].terms(sign), e.operands[1].terms(sign)...)
+	case "-":
+		return append(e.operands[0].terms(sign), e.operands[1].terms(-sign)...)
+	case "neg":
+		return e.operands[0].terms(-sign)
+	}
+	t := term{coefficient: sign}
+	t.multiply(e, 1)
+	return []term{t}
+}
+
+// Multiply a term by an expression raised to a power, combining like factors
+func (t *term) multiply(e *expr, power float64) {
+	switch {
+	case e.op == "num":
+		t.coefficient *= math.Pow(e.value, power)
+		return
+	case e.op == "neg":
+		t.coefficient = -t.coefficient
+		t.multiply(e.operands[0], power)
+		return
+	case e.op == "*":
+		t.multiply(e.operands[0], power)
+		t.multiply(e.operands[1], power)
+		return
+	case e.op == "/":
+		t.multiply(e.operands[0], power)
+		t.multiply(e.operands[1], -power)
+		return
+	case e.op == "^" && e.operands[1].op == "num":
+		t.multiply(e.operands[0], power*e.operands[1].value)
+		return
+	}
+	key := e.String()
+	for i := range t.factors {
+		if t.factors[i].base.String() == key {
+			t.factors[i].power += power
+			return
+		}
+	}
+	t.factors = append(t.factors, factor{base: e, power: power})
+}
+
+func (t term) key() string {
+	parts := []string{}
+	for _, f := range t.factors {
+		if f.power != 0 {
+			parts = append(parts, fmt.Sprintf("%s^%g", f.base.String(), f.power))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "*")
+}
+
+func (t term) degree() float64 {
+	degree := 0.0
+	for _, f := range t.factors {
+		degree += f.power
+	}
+	return degree
+}
+
+// Rebuild a term with the given coefficient, as c*x^n*y/z
+func (t term) expr(coefficient float64) *expr {
+	var numerator, denominator *expr
+	for _, f := range t.factors {
+		if f.power == 0 {
+			continue
+		}
+		power := math.Abs(f.power)
+		part := f.base
+		if power != 1 {
+			part = binary("^", f.base, number(power))
+		}
+		if f.power > 0 {
+			numerator = multiplied(numerator, part)
+		} else {
+			denominator = multiplied(denominator, part)
+		}
+	}
+	if coefficient != 1 || numerator == nil {
+		numerator = multiplied(number(coefficient), numerator)
+	}
+	if denominator != nil {
+		return binary("/", numerator, denominator)
+	}
+	return numerator
+}
+
+func multiplied(y *expr, x *expr) *expr {
+	if y == nil {
+		return x
+	}
+	if x == nil {
+		return y
+	}
+	return binary("*", y, x)
+}
+
+// Translate the expression to RPN values in evaluation order
+func (e *expr) rpn(values []CoreValue, rom *Rom) []CoreValue {
+	switch e.op {
+	case "num":
+		return append(values, FloatValue{value: e.value})
+	case "var":
+		return append(values, ReferenceValue{value: e.name})
+	}
+	for _, operand := range e.operands {
+		values = operand.rpn(values, rom)
+	}
+	switch e.op {
+	case "fn":
+		return append(values, rom.RawToInstruction(e.name))
+	case "neg":
+		return append(values, rom.RawToInstruction("neg"))
+	}
+	return append(values, rom.RawToInstruction(e.op))
+}
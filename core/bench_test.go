@@ -0,0 +1,43 @@
+package core_test
+
+import (
+	"context"
+	"dmccaffrey/28z/core"
+	"testing"
+)
+
+// Time a ROM program evaluated directly and compiled, on a fresh VM for each evaluation
+func benchmarkProgram(b *testing.B, path string) {
+	rom, err := core.LoadRom("../rom")
+	if err != nil {
+		b.Fatalf("Failed to load ROM: %s", err)
+	}
+	program, err := rom.LoadProgram(path)
+	if err != nil {
+		b.Fatalf("Failed to load program: %s", err)
+	}
+	for _, mode := range []struct {
+		name     string
+		compiled bool
+	}{{"interpreted", false}, {"compiled", true}} {
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				vm := core.NewVM(rom)
+				vm.SetCompiled(mode.compiled)
+				b.StartTimer()
+				if _, err := vm.EvalProgram(context.Background(), program); err != nil {
+					b.Fatalf("Failed to evaluate program: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGraph(b *testing.B) {
+	benchmarkProgram(b, "../rom/tests/graph.28")
+}
+
+func BenchmarkGameLoop(b *testing.B) {
+	benchmarkProgram(b, "../rom/bench/game-loop.28")
+}
@@ -0,0 +1,262 @@
+package core
+
+import "sync"
+
+// The operations of a compiled sequence
+type opcode uint8
+
+const (
+	// Push the value at the source position
+	opPush opcode = iota
+	// Push the value referred to by the reference at the source position
+	opReference
+	// Evaluate the instruction arg
+	opInstruction
+	// Step past the sequence literal at the source position, which is inlined after the
+	// ceval or ceval2 that follows it
+	opLiteral
+	// Evaluate the condition of the ceval or ceval2 instruction arg, continuing after the
+	// op when it is true, at target when it is false, or at end in storing mode
+	opBranch
+	// Enter the inlined sequence arg in a frame of its own, which lasts until target
+	opEnter
+	// Leave the inlined sequence
+	opLeave
+	// Continue at target
+	opJump
+)
+
+type (
+	op struct {
+		code opcode
+		// The position in the sequence being evaluated of the value the op was compiled from
+		source int32
+		arg    int32
+		target int32
+		end    int32
+	}
+	// A sequence compiled to ops that run in order, with the instructions it refers to
+	// resolved, and the bodies of ceval and ceval2 inlined behind jumps
+	bytecode struct {
+		ops          []op
+		instructions []InstructionValue
+		sequences    []SequenceValue
+	}
+	// The compiled form of a sequence, shared by its copies and compiled on first use
+	compiled struct {
+		once sync.Once
+		code *bytecode
+	}
+)
+
+// Create a sequence that is compiled the first time it is evaluated
+func compiledSequence(values []CoreValue) SequenceValue {
+	return SequenceValue{value: values, code: &compiled{}}
+}
+
+func (c *compiled) get(sequence []CoreValue) *bytecode {
+	c.once.Do(func() {
+		c.code = compile(sequence)
+	})
+	return c.code
+}
+
+func compile(sequence []CoreValue) *bytecode {
+	b := &bytecode{}
+	b.emit(sequence)
+	return b
+}
+
+// Append the ops of a sequence, which is evaluated from its end
+func (b *bytecode) emit(sequence []CoreValue) {
+	for i := len(sequence) - 1; i >= 0; i-- {
+		if count := conditionalBodies(sequence, i); count != 0 {
+			b.emitConditional(sequence, i, count)
+			i -= count
+			continue
+		}
+		switch v := sequence[i].(type) {
+		case InstructionValue:
+			b.instructions = append(b.instructions, v)
+			b.ops = append(b.ops, op{code: opInstruction, source: int32(i), arg: int32(len(b.instructions) - 1)})
+		case ReferenceValue:
+			b.ops = append(b.ops, op{code: opReference, source: int32(i)})
+		default:
+			b.ops = append(b.ops, op{code: opPush, source: int32(i)})
+		}
+	}
+}
+
+// The instructions whose sequence arguments are inlined, by the number of sequences
+var conditionals = map[int]string{1: "ceval", 2: "ceval2"}
+
+// Count the sequence literals starting at i that are the bodies of a ceval or ceval2
+// following them, or return 0
+func conditionalBodies(sequence []CoreValue, i int) int {
+	for count := 1; count <= 2 && i-count >= 0; count++ {
+		instruction, ok := sequence[i-count].(InstructionValue)
+		if !ok || instruction.name != conditionals[count] {
+			continue
+		}
+		for j := 0; j < count; j++ {
+			if _, ok := sequence[i-j].(SequenceValue); !ok {
+				return 0
+			}
+		}
+		return count
+	}
+	return 0
+}
+
+// Append the ops of the count sequence literals starting at i and the ceval or ceval2
+// after them, evaluating the first literal when the condition is true
+func (b *bytecode) emitConditional(sequence []CoreValue, i int, count int) {
+	for j := 0; j < count; j++ {
+		b.ops = append(b.ops, op{code: opLiteral, source: int32(i - j)})
+	}
+	b.instructions = append(b.instructions, sequence[i-count].(InstructionValue))
+	branch := len(b.ops)
+	b.ops = append(b.ops, op{code: opBranch, source: int32(i - count), arg: int32(len(b.instructions) - 1)})
+	jumps := []int{}
+	for j := 0; j < count; j++ {
+		if j == 1 {
+			b.ops[branch].target = int32(len(b.ops))
+		}
+		b.sequences = append(b.sequences, sequence[i-j].(SequenceValue))
+		enter := len(b.ops)
+		b.ops = append(b.ops, op{code: opEnter, source: int32(i - count), arg: int32(len(b.sequences) - 1)})
+		b.emit(sequence[i-j].(SequenceValue).value)
+		b.ops[enter].target = int32(len(b.ops))
+		b.ops = append(b.ops, op{code: opLeave, source: int32(i - count)})
+		if j == 0 && count == 2 {
+			jumps = append(jumps, len(b.ops))
+			b.ops = append(b.ops, op{code: opJump, source: int32(i - count)})
+		}
+	}
+	end := int32(len(b.ops))
+	if count == 1 {
+		b.ops[branch].target = end
+	}
+	b.ops[branch].end = end
+	for _, jump := range jumps {
+		b.ops[jump].target = end
+	}
+}
+
+// Evaluate a sequence in a frame of its own, running its compiled form when it has one
+func (c *Core) evalSequence(name string, sequence SequenceValue, locals map[string]CoreValue) bool {
+	if sequence.code == nil || c.interpret || c.Regs.Mode == Storing {
+		return c.evalFrame(name, sequence.value, locals)
+	}
+	return c.run(name, sequence.value, sequence.code.get(sequence.value), locals)
+}
+
+// Evaluate a value in an unnamed frame
+func (c *Core) evalBody(value CoreValue) bool {
+	if sequence, ok := value.(SequenceValue); ok {
+		return c.evalSequence("", sequence, nil)
+	}
+	return c.EvalSequence(value.GetSequence())
+}
+
+// Run compiled ops, with the same effects as evaluating the sequence with evalFrame
+func (c *Core) run(name string, sequence []CoreValue, code *bytecode, locals map[string]CoreValue) bool {
+	if !c.checkFrames() {
+		return false
+	}
+	base := len(c.frames)
+	prevSequence := c.env.currentSequence
+	c.env.currentSequence = sequence
+	c.frames = append(c.frames, Frame{Name: name, Sequence: sequence, Position: len(sequence) - 1, Locals: locals})
+	defer func() {
+		c.env.currentSequence = prevSequence
+		c.frames = c.frames[:base]
+	}()
+
+	// The ends of the inlined sequences being evaluated, where a break continues
+	leaves := []int32{}
+	for pc := 0; pc < len(code.ops); pc++ {
+		o := &code.ops[pc]
+		frame := &c.frames[len(c.frames)-1]
+		switch o.code {
+		case opEnter:
+			body := code.sequences[o.arg]
+			c.frames = append(c.frames, Frame{Name: body.name, Sequence: body.value, Position: len(body.value) - 1})
+			c.env.currentSequence = body.value
+			leaves = append(leaves, o.target)
+			continue
+		case opLeave:
+			c.frames = c.frames[:len(c.frames)-1]
+			c.env.currentSequence = c.frames[len(c.frames)-1].Sequence
+			leaves = leaves[:len(leaves)-1]
+			continue
+		case opJump:
+			pc = int(o.target) - 1
+			continue
+		}
+
+		frame.Position = int(o.source)
+		if c.interrupted() || c.failed() || !c.checkBudget() {
+			return false
+		}
+		value := frame.Sequence[o.source]
+		if c.Debugger != nil {
+			c.Debugger.check(c, value)
+		}
+		switch o.code {
+		case opPush:
+			c.Push(value)
+		case opReference:
+			c.Push(value.(ReferenceValue).Dereference(c))
+		case opLiteral:
+			if c.Regs.Mode == Storing {
+				c.Push(value)
+			}
+		case opInstruction:
+			if !c.execute(code.instructions[o.arg]) {
+				return false
+			}
+		case opBranch:
+			instruction := code.instructions[o.arg]
+			if c.Regs.Mode == Storing {
+				c.execute(instruction)
+				pc = int(o.end) - 1
+			} else if condition, ok := consumeCondition(c, 0); !ok {
+				c.instruction = instruction.name
+				c.raise(ErrInstructionFailed, "Expected a boolean condition")
+				return false
+			} else if !condition {
+				pc = int(o.target) - 1
+			}
+		}
+		if c.ShouldBreak() {
+			if len(leaves) == 0 {
+				return false
+			}
+			pc = int(leaves[len(leaves)-1]) - 1
+		}
+	}
+	return true
+}
+
+// Evaluate an instruction as ProcessInstruction does, returning false if it could not start
+func (c *Core) execute(instruction InstructionValue) bool {
+	c.instruction = instruction.name
+	if instruction.value.argCount > c.currentStack().length {
+		c.raise(ErrTooFewArguments, "Too few arguments")
+		return false
+	}
+	if !instruction.value.IsValid() {
+		c.raise(ErrInvalidInstruction, "Not a valid instruction")
+		return true
+	}
+	if c.Regs.Mode == Storing {
+		c.Push(instruction)
+		return true
+	}
+	if result := instruction.value.impl(c); result.error {
+		Logger.Printf("Error: Error evaluating instruction: value=%s, err=%s", instruction.value.description, result.message)
+		c.raise(ErrInstructionFailed, result.message)
+	}
+	return true
+}
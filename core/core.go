@@ -1,6 +1,9 @@
 package core
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
 
 const (
 	Running ExecutionMode = 1
@@ -14,6 +17,13 @@ const (
 	Clear                         = 3
 	StateUpdated                  = 4
 	Output                        = 5
+	Paused                        = 6
+)
+
+const (
+	interruptNone      int32 = 0
+	interruptRequested       = 1
+	interruptHandled         = 2
 )
 
 const (
@@ -37,16 +47,40 @@ type (
 		Ram         []byte
 		Regs        Registers
 		Error       CoreValue
-		ticker100ms time.Ticker
-		ticker1s    time.Ticker
+		LastError   ErrorValue
+		ticker100ms *time.Ticker
+		ticker1s    *time.Ticker
 		Input       chan string
 		Control     chan CommandMessage
+		Console     func(CommandMessage)
+		Debugger    *Debugger
 		Ticks       int64
+		env         *Environment
+		frames      []Frame
+		instruction string
+		interrupt   atomic.Int32
+		Quotas      Quotas
+		// The directory the save and restore instructions read and write files in, where
+		// empty means the working directory
+		SnapshotDir string
+		usage       quotaUsage
+		// Set to evaluate sequences directly, rather than running their compiled form
+		interpret bool
 	}
 	Registers struct {
 		State       StateRegister
 		Mode        ExecutionMode
 		LoopCounter int16
+		// Digits carried by decimals
+		Precision int
+		// Base integers are shown in, and the bits they are wrapped to
+		Base     int
+		WordSize int
+		// How floats are shown, with the digits used by fix, sci and eng
+		Display DisplayMode
+		Digits  int
+		// Set when comparisons set the result flag rather than pushing a boolean
+		FlagMode bool
 	}
 	StateRegister struct {
 		ResultFlag bool
@@ -59,17 +93,29 @@ type (
 	}
 )
 
-func NewCore() *Core {
+// Create a core driven through the Input and Control channels, with tickers running
+func NewCore(rom *Rom) *Core {
+	core := newCore(rom)
+	core.ticker100ms = time.NewTicker(100 * time.Millisecond)
+	core.ticker1s = time.NewTicker(1 * time.Second)
+	core.Input = make(chan string, 16)
+	core.Control = make(chan CommandMessage)
+	go core.inputHandler()
+	return core
+}
+
+func newCore(rom *Rom) *Core {
 	core := Core{}
+	core.env = NewEnvironment(rom)
 	core.NewStack()
 	core.Regs.Mode = Running
+	core.Regs.Precision = DefaultPrecision
+	core.Regs.Base = 10
+	core.Regs.WordSize = 64
+	core.Error = DefaultValue{}
+	core.Quotas = DefaultQuotas
 	core.Ram = make([]byte, 8192)
-	core.ticker100ms = *time.NewTicker(100 * time.Millisecond)
-	core.ticker1s = *time.NewTicker(1 * time.Second)
-	core.Input = make(chan string)
-	core.Control = make(chan CommandMessage)
 	core.Ticks = 0
-	go core.inputHandler()
 	return &core
 }
 
@@ -78,19 +124,19 @@ func (c *Core) inputHandler() {
 		select {
 		case <-c.ticker100ms.C:
 			c.Ticks += 1
-			value, ok := Variables["tick100ms"]
+			value, ok := c.env.Variable("tick100ms")
 			if !ok {
 				continue
 			}
 			Logger.Printf("Evaluating sequence for 100ms ticker\n")
-			c.EvalSequenceIsolated(value.GetSequence())
+			c.evalTick(value)
 		case <-c.ticker1s.C:
-			value, ok := Variables["tick1s"]
+			value, ok := c.env.Variable("tick1s")
 			if !ok {
 				continue
 			}
 			Logger.Printf("Evaluating sequence for 1s ticker\n")
-			c.EvalSequenceIsolated(value.GetSequence())
+			c.evalTick(value)
 		case input := <-c.Input:
 			c.ProcessRaw(input)
 			c.Control <- CommandMessage{Command: StateUpdated, Arg: ""}
@@ -99,18 +145,86 @@ func (c *Core) inputHandler() {
 	}
 }
 
+func (c *Core) Env() *Environment {
+	return c.env
+}
+
 func (c *Core) Halt() {
+	if c.ticker100ms == nil {
+		return
+	}
 	c.ticker100ms.Stop()
 	c.ticker1s.Stop()
 }
 
+// Request that the evaluation in progress stops at the next instruction boundary
+func (c *Core) Interrupt() {
+	Logger.Printf("Interrupt requested\n")
+	c.interrupt.Store(interruptRequested)
+}
+
+// Check for an interrupt at an instruction boundary, recording where evaluation stopped
+func (c *Core) interrupted() bool {
+	if c.interrupt.Load() == interruptNone {
+		return false
+	}
+	if c.interrupt.CompareAndSwap(interruptRequested, interruptHandled) {
+		location := "top level"
+		if len(c.frames) != 0 {
+			location = c.frames[len(c.frames)-1].String()
+		}
+		Logger.Printf("Evaluation interrupted: location=%s\n", location)
+		c.raise(ErrInterrupted, "Interrupted at "+location)
+	}
+	return true
+}
+
+// Run an evaluation started from outside the VM, unwinding any stacks it pushed if it fails
+func (c *Core) evalTopLevel(eval func()) {
+	c.interrupt.Store(interruptNone)
+	c.unsetError()
+	c.resetUsage()
+	depth := c.stackStack.length
+	eval()
+	c.interrupt.Store(interruptNone)
+	if c.failed() {
+		for c.stackStack.length > depth {
+			c.DropStack()
+		}
+	}
+}
+
+// Evaluate a ticker sequence without replacing an error raised by earlier input
+func (c *Core) evalTick(value CoreValue) {
+	prevError := c.Error
+	c.evalTopLevel(func() { c.EvalSequenceIsolated(value.GetSequence()) })
+	if !c.failed() {
+		c.Error = prevError
+	}
+}
+
+// Send a command to the console handler, or the Control channel if there is none
+func (c *Core) Emit(command ExecutionCommand, arg string) {
+	message := CommandMessage{Command: command, Arg: arg}
+	if c.Console != nil {
+		c.Console(message)
+		return
+	}
+	c.Control <- message
+}
+
 func (c *Core) currentStack() *Stack[CoreValue] {
 	return c.stackStack.Peek()
 }
 
-func (c *Core) NewStack() {
+// Push a new stack, returning false without pushing it if the stack depth quota is exceeded
+func (c *Core) NewStack() bool {
+	if !c.checkStackDepth() {
+		return false
+	}
 	c.prevStack = c.stackStack.Peek()
 	c.stackStack.Push(NewCoreValueStack())
+	return true
 }
 
 func (c *Core) DropStack() {
@@ -127,9 +241,35 @@ func (c *Core) DropStack() {
 	}
 }
 
+// Process a line of input, token by token, stopping at the first token that fails
 func (c *Core) ProcessRaw(input string) {
+	c.evalTopLevel(func() {
+		tokens, err := Tokenize(input)
+		if err != nil {
+			Logger.Printf("Error: Could not tokenize input: input=%s, err=%s\n", input, err)
+			c.raise(ErrInvalidInput, err.Error())
+			return
+		}
+		for _, token := range tokens {
+			c.processToken(token)
+			if c.failed() {
+				return
+			}
+		}
+	})
+}
+
+func (c *Core) processToken(token Token) {
+	Logger.Printf("Processing token: line=%d, column=%d\n", token.Line, token.Column)
+	if token.Quoted {
+		c.Push(StringValue{value: token.Text})
+		return
+	}
+	c.processRaw(token.Text)
+}
+
+func (c *Core) processRaw(input string) {
 	Logger.Printf("Processing raw input: input=%s\n", input)
-	c.unsetError()
 	if input == "" {
 		Logger.Printf("Error: Empty input provided")
 		return
@@ -143,7 +283,7 @@ func (c *Core) ProcessRaw(input string) {
 		runReference = true
 	}
 
-	value := RawToInstruction(input)
+	value := c.env.rom.RawToInstruction(input)
 	if value.GetType() == InstructionType {
 		c.ProcessInstruction(value.(InstructionValue))
 		return
@@ -152,7 +292,19 @@ func (c *Core) ProcessRaw(input string) {
 	value = RawToImmediateCoreValue(input)
 	if value.GetType() == DefaultType {
 		Logger.Printf("Error: Not a valid input: input=%s\n", input)
-		c.setError("Not a valid input")
+		if input[0] == '[' || input[0] == '{' {
+			if _, err := parseLiteral(input); err != nil {
+				c.raise(ErrInvalidInput, "Invalid literal: "+err.Error())
+				return
+			}
+		}
+		if text, ok := algebraicText(input); ok {
+			if _, err := NewAlgebraicValue(text); err != nil {
+				c.raise(ErrInvalidInput, "Invalid expression: "+err.Error())
+				return
+			}
+		}
+		c.raise(ErrInvalidInput, "Not a valid input")
 		return
 	}
 
@@ -167,7 +319,7 @@ func (c *Core) ProcessRaw(input string) {
 	}
 
 	if runReference {
-		c.EvalSequence(value.GetSequence())
+		c.EvalValue(value)
 		return
 	}
 
@@ -179,7 +331,7 @@ func (c *Core) ProcessInstruction(instruction InstructionValue) {
 	impl := instruction.value
 	if !impl.IsValid() {
 		Logger.Printf("Error: Instruction is not valid: value=%s", instruction.value.description)
-		c.setError("Not a valid instruction")
+		c.raise(ErrInvalidInstruction, "Not a valid instruction")
 		return
 	}
 
@@ -189,10 +341,11 @@ func (c *Core) ProcessInstruction(instruction InstructionValue) {
 		return
 	}
 
+	c.instruction = instruction.name
 	if impl.argCount > c.currentStack().Len() {
 		Logger.Printf("Error: Too few arguments for instruction: value=%s, have=%d, required=%d",
 			instruction.value.description, c.currentStack().Len(), instruction.value.argCount)
-		c.setError("Too few arguments")
+		c.raise(ErrTooFewArguments, "Too few arguments")
 		return
 	}
 
@@ -200,19 +353,10 @@ func (c *Core) ProcessInstruction(instruction InstructionValue) {
 	result := impl.impl(c)
 	if result.error {
 		Logger.Printf("Error: Error evaluating instruction: value=%s, err=%s", instruction.value.description, result.message)
-		c.setError(result.message)
+		c.raise(ErrInstructionFailed, result.message)
 	}
 }
 
-func (c *Core) setError(error string) {
-	c.Error = StringValue{value: error}
-	c.Regs.Mode = Halted
-}
-
-func (c *Core) unsetError() {
-	c.Error = DefaultValue{}
-}
-
 func (c *Core) GetStackString() string {
 	result := "Stack: "
 	stack := c.currentStack().ToArray()
@@ -220,7 +364,7 @@ func (c *Core) GetStackString() string {
 		if stack[i] == nil {
 			continue
 		}
-		str := stack[i].GetString()
+		str := c.Format(stack[i])
 		result += str + ", "
 	}
 
@@ -228,7 +372,10 @@ func (c *Core) GetStackString() string {
 }
 
 func (c *Core) Push(value CoreValue) {
-	c.currentStack().Push(value)
+	if !c.checkStackEntries() || !c.checkValueBytes(value) {
+		return
+	}
+	c.currentStack().Push(c.wrap(value))
 }
 
 func (c *Core) Pop() *CoreValue {
@@ -245,22 +392,30 @@ func (c *Core) GetStackArray() []CoreValue {
 }
 
 func (c *Core) Store(key CoreValue, value CoreValue) {
-	if key.GetType() == FloatType {
+	if isNumeric(key) {
 		index := key.GetInt()
 		if index < 0 || index >= len(c.Ram) {
 			Logger.Printf("Error: store out of range for RAM: index=%d", index)
-			c.setError("RAM offset too large")
+			c.raise(ErrInstructionFailed, "RAM offset too large")
 			return
 		}
 		c.Ram[index] = byte(value.GetInt())
 		return
 	}
 	if key.GetType() == StringType {
-		Variables[key.GetString()] = value
+		if locals := c.localScope(key.GetString()); locals != nil {
+			locals[key.GetString()] = value
+			return
+		}
+		if !c.visible(key.GetString()) {
+			c.raise(ErrInstructionFailed, "Variable not visible")
+			return
+		}
+		c.setVariable(key.GetString(), value)
 		return
 	}
 	Logger.Printf("Invalid key type: %s", key)
-	c.setError("Invalid key type")
+	c.raise(ErrInstructionFailed, "Invalid key type")
 }
 
 func (c *Core) ShouldBreak() bool {
@@ -278,22 +433,60 @@ func (c *Core) StackDepth() int {
 }
 
 func (c *Core) EvalSequenceIsolated(sequence []CoreValue) bool {
-	c.NewStack()
+	if !c.NewStack() {
+		return false
+	}
 	result := c.EvalSequence(sequence)
 	c.DropStack()
 	return result
 }
 
+// Evaluate a value, naming the frame after the program or variable it came from
+func (c *Core) EvalValue(value CoreValue) bool {
+	if sequence, ok := value.(SequenceValue); ok {
+		return c.evalSequence(sequence.name, sequence, nil)
+	}
+	if algebraic, ok := value.(AlgebraicValue); ok {
+		c.evalAlgebraic(algebraic)
+		return !c.failed()
+	}
+	return c.EvalSequence(value.GetSequence())
+}
+
 func (c *Core) EvalSequence(sequence []CoreValue) bool {
-	end := len(sequence) - 1
+	return c.evalFrame("", sequence, nil)
+}
 
-	Logger.Printf("Evaluating sequence: len=%d, value=%s\n", len(sequence), sequence)
+func (c *Core) evalFrame(name string, sequence []CoreValue, locals map[string]CoreValue) bool {
+	if !c.checkFrames() {
+		return false
+	}
+	end := len(sequence) - 1
+	prevSequence := c.env.currentSequence
+	c.env.currentSequence = sequence
+	c.frames = append(c.frames, Frame{Name: name, Sequence: sequence, Position: end, Locals: locals})
+	defer func() {
+		c.env.currentSequence = prevSequence
+		c.frames = c.frames[:len(c.frames)-1]
+	}()
+
+	Logger.Printf("Evaluating sequence: name=%s, len=%d, value=%s\n", name, len(sequence), sequence)
 	for i := end; i >= 0; i-- {
 		val := sequence[i]
+		c.frames[len(c.frames)-1].Position = i
+		if c.interrupted() || c.failed() || !c.checkBudget() {
+			return false
+		}
+		if c.Debugger != nil {
+			c.Debugger.check(c, val)
+		}
 		switch val.GetType() {
 		case InstructionType:
 			Logger.Printf("[%d] Evaluating instruction: value=%s\n", i, val.GetString())
 			if !val.(InstructionValue).CheckArgs(c) {
+				Logger.Printf("Error: Too few arguments for instruction: value=%s\n", val.GetString())
+				c.instruction = val.(InstructionValue).name
+				c.raise(ErrTooFewArguments, "Too few arguments")
 				return false
 			}
 			c.ProcessInstruction(val.(InstructionValue))
@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -15,6 +16,16 @@ const (
 	InstructionType               = 4
 	ReferenceType                 = 5
 	DefaultType                   = 6
+	ErrorType                     = 7
+	IntegerType                   = 8
+	BoolType                      = 9
+	MapType                       = 10
+	ComplexType                   = 11
+	ArrayType                     = 12
+	QuantityType                  = 13
+	AlgebraicType                 = 14
+	RationalType                  = 15
+	DecimalType                   = 16
 )
 
 type (
@@ -30,6 +41,48 @@ type (
 		DefaultValue
 		value float64
 	}
+	IntegerValue struct {
+		DefaultValue
+		value int64
+	}
+	ComplexValue struct {
+		DefaultValue
+		value complex128
+	}
+	// An exact fraction such as 1/3, never with a denominator of 1
+	RationalValue struct {
+		DefaultValue
+		value *big.Rat
+	}
+	// An arbitrary precision number such as 1.5d, shown with the given number of digits
+	DecimalValue struct {
+		DefaultValue
+		value  *big.Float
+		digits int
+	}
+	// A vector, or a matrix with its elements stored row by row
+	ArrayValue struct {
+		DefaultValue
+		rows   int
+		cols   int
+		vector bool
+		data   []float64
+	}
+	// A number carrying units, such as 9.8_m/s^2
+	QuantityValue struct {
+		DefaultValue
+		value float64
+		units Units
+	}
+	// A symbolic expression such as 'x^2+3*x'
+	AlgebraicValue struct {
+		DefaultValue
+		value *expr
+	}
+	BoolValue struct {
+		DefaultValue
+		value bool
+	}
 	StringValue struct {
 		DefaultValue
 		value string
@@ -37,15 +90,33 @@ type (
 	SequenceValue struct {
 		DefaultValue
 		value []CoreValue
+		name  string
+		// The compiled form of the sequence, or nil to always evaluate it directly
+		code *compiled
+	}
+	// Maps are immutable, with entries kept in insertion order
+	MapValue struct {
+		DefaultValue
+		keys   []CoreValue
+		values []CoreValue
+		index  map[string]int
 	}
 	InstructionValue struct {
 		DefaultValue
 		value Instruction
+		name  string
 	}
 	ReferenceValue struct {
 		DefaultValue
 		value string
 	}
+	ErrorValue struct {
+		DefaultValue
+		code        ErrorCode
+		message     string
+		instruction string
+		trace       []string
+	}
 )
 
 // Default
@@ -90,6 +161,341 @@ func (f FloatValue) GetSequence() []CoreValue {
 	return []CoreValue{f}
 }
 
+// Integer
+func (i IntegerValue) GetFloat() float64 {
+	return float64(i.value)
+}
+
+func (i IntegerValue) GetString() string {
+	return strconv.FormatInt(i.value, 10)
+}
+
+func (i IntegerValue) GetInt() int {
+	return int(i.value)
+}
+
+func (i IntegerValue) GetType() CoreValueType {
+	return IntegerType
+}
+
+func (i IntegerValue) GetSequence() []CoreValue {
+	return []CoreValue{i}
+}
+
+// Check if a value is a float, an integer, a rational or a decimal
+func isNumeric(value CoreValue) bool {
+	switch value.GetType() {
+	case FloatType, IntegerType, RationalType, DecimalType:
+		return true
+	}
+	return false
+}
+
+// Rational
+func NewRationalValue(numerator int64, denominator int64) CoreValue {
+	return newRational(big.NewRat(numerator, denominator))
+}
+
+// Create a rational, or an integer when the denominator is 1
+func newRational(r *big.Rat) CoreValue {
+	if r.IsInt() && r.Num().IsInt64() {
+		return IntegerValue{value: r.Num().Int64()}
+	}
+	return RationalValue{value: r}
+}
+
+func (r RationalValue) GetFloat() float64 {
+	value, _ := r.value.Float64()
+	return value
+}
+
+func (r RationalValue) GetString() string {
+	return r.value.RatString()
+}
+
+func (r RationalValue) GetInt() int {
+	return int(r.GetFloat())
+}
+
+func (r RationalValue) GetType() CoreValueType {
+	return RationalType
+}
+
+func (r RationalValue) GetSequence() []CoreValue {
+	return []CoreValue{r}
+}
+
+// Decimal
+func NewDecimalValue(input string) (DecimalValue, error) {
+	value := RawToImmediateCoreValue(input + "d")
+	if value.GetType() != DecimalType {
+		return DecimalValue{}, fmt.Errorf("invalid decimal: %s", input)
+	}
+	return value.(DecimalValue), nil
+}
+
+func (d DecimalValue) GetFloat() float64 {
+	value, _ := d.value.Float64()
+	return value
+}
+
+func (d DecimalValue) GetString() string {
+	return d.value.Text('g', d.digits) + "d"
+}
+
+func (d DecimalValue) GetInt() int {
+	return int(d.GetFloat())
+}
+
+func (d DecimalValue) GetType() CoreValueType {
+	return DecimalType
+}
+
+func (d DecimalValue) GetSequence() []CoreValue {
+	return []CoreValue{d}
+}
+
+// Get a numeric value as an integer, truncating floats
+func integerOf(value CoreValue) int64 {
+	if i, ok := value.(IntegerValue); ok {
+		return i.value
+	}
+	return int64(value.GetFloat())
+}
+
+// Complex
+func (c ComplexValue) GetFloat() float64 {
+	return real(c.value)
+}
+
+func (c ComplexValue) GetString() string {
+	return "(" + strconv.FormatFloat(real(c.value), 'E', -1, 64) + "," + strconv.FormatFloat(imag(c.value), 'E', -1, 64) + ")"
+}
+
+func (c ComplexValue) GetInt() int {
+	return int(real(c.value))
+}
+
+func (c ComplexValue) GetType() CoreValueType {
+	return ComplexType
+}
+
+func (c ComplexValue) GetSequence() []CoreValue {
+	return []CoreValue{c}
+}
+
+// Check if a value is a number that can be used as a complex number
+func isComplex(value CoreValue) bool {
+	return value.GetType() == ComplexType || isNumeric(value)
+}
+
+func complexOf(value CoreValue) complex128 {
+	if c, ok := value.(ComplexValue); ok {
+		return c.value
+	}
+	return complex(value.GetFloat(), 0)
+}
+
+// Array
+func NewVectorValue(values []float64) ArrayValue {
+	return ArrayValue{rows: 1, cols: len(values), vector: true, data: append([]float64{}, values...)}
+}
+
+func NewMatrixValue(rows [][]float64) ArrayValue {
+	m := ArrayValue{rows: len(rows)}
+	if len(rows) > 0 {
+		m.cols = len(rows[0])
+	}
+	for _, row := range rows {
+		m.data = append(m.data, row...)
+	}
+	return m
+}
+
+func newMatrix(rows int, cols int) ArrayValue {
+	return ArrayValue{rows: rows, cols: cols, data: make([]float64, rows*cols)}
+}
+
+func (a ArrayValue) GetFloat() float64 {
+	if len(a.data) == 0 {
+		return 0
+	}
+	return a.data[0]
+}
+
+func (a ArrayValue) GetInt() int {
+	return int(a.GetFloat())
+}
+
+// Describe the array in its literal form, such as |1,2;3,4|
+func (a ArrayValue) GetString() string {
+	return a.describe(func(value float64) string {
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	})
+}
+
+// Describe the array between bars, with rows separated by semicolons
+func (a ArrayValue) describe(cell func(float64) string) string {
+	var sb strings.Builder
+	sb.WriteString("|")
+	for i := 0; i < a.rows; i++ {
+		if i > 0 {
+			sb.WriteString(";")
+		}
+		for j := 0; j < a.cols; j++ {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(cell(a.At(i, j)))
+		}
+	}
+	sb.WriteString("|")
+	return sb.String()
+}
+
+func (a ArrayValue) GetType() CoreValueType {
+	return ArrayType
+}
+
+func (a ArrayValue) GetSequence() []CoreValue {
+	return []CoreValue{a}
+}
+
+// Describe the array with one line per row and aligned columns
+func (a ArrayValue) lines(cell func(float64) string) []string {
+	width := 0
+	cells := make([]string, len(a.data))
+	for i, value := range a.data {
+		cells[i] = cell(value)
+		width = max(width, len(cells[i]))
+	}
+	lines := make([]string, a.rows)
+	for i := 0; i < a.rows; i++ {
+		var sb strings.Builder
+		sb.WriteString("[")
+		for j := 0; j < a.cols; j++ {
+			sb.WriteString(fmt.Sprintf(" %*s", width, cells[i*a.cols+j]))
+		}
+		sb.WriteString(" ]")
+		lines[i] = sb.String()
+	}
+	return lines
+}
+
+func (a ArrayValue) Rows() int {
+	return a.rows
+}
+
+func (a ArrayValue) Cols() int {
+	return a.cols
+}
+
+func (a ArrayValue) IsVector() bool {
+	return a.vector
+}
+
+func (a ArrayValue) At(row int, col int) float64 {
+	return a.data[row*a.cols+col]
+}
+
+// Quantity
+func NewQuantityValue(value float64, units string) (QuantityValue, error) {
+	u, err := ParseUnits(units)
+	if err != nil {
+		return QuantityValue{}, err
+	}
+	return QuantityValue{value: value, units: u}, nil
+}
+
+func (q QuantityValue) GetFloat() float64 {
+	return q.value
+}
+
+func (q QuantityValue) GetString() string {
+	return strconv.FormatFloat(q.value, 'E', -1, 64) + "_" + q.units.String()
+}
+
+func (q QuantityValue) GetInt() int {
+	return int(q.value)
+}
+
+func (q QuantityValue) GetType() CoreValueType {
+	return QuantityType
+}
+
+func (q QuantityValue) GetSequence() []CoreValue {
+	return []CoreValue{q}
+}
+
+func (q QuantityValue) Units() string {
+	return q.units.String()
+}
+
+// Algebraic
+func NewAlgebraicValue(input string) (AlgebraicValue, error) {
+	e, err := parseAlgebraic(input)
+	if err != nil {
+		return AlgebraicValue{}, err
+	}
+	return AlgebraicValue{value: e}, nil
+}
+
+func (a AlgebraicValue) GetFloat() float64 {
+	value, _ := a.value.evaluate(nil)
+	return value
+}
+
+func (a AlgebraicValue) GetString() string {
+	return "'" + a.value.String() + "'"
+}
+
+func (a AlgebraicValue) GetInt() int {
+	return int(a.GetFloat())
+}
+
+func (a AlgebraicValue) GetType() CoreValueType {
+	return AlgebraicType
+}
+
+func (a AlgebraicValue) GetSequence() []CoreValue {
+	return []CoreValue{a}
+}
+
+// Bool
+func (b BoolValue) GetFloat() float64 {
+	return float64(b.GetInt())
+}
+
+func (b BoolValue) GetString() string {
+	return strconv.FormatBool(b.value)
+}
+
+func (b BoolValue) GetInt() int {
+	if b.value {
+		return 1
+	}
+	return 0
+}
+
+func (b BoolValue) GetType() CoreValueType {
+	return BoolType
+}
+
+func (b BoolValue) GetSequence() []CoreValue {
+	return []CoreValue{b}
+}
+
+// Get the truth of a boolean or a number, where any non-zero number is true
+func truthOf(value CoreValue) (bool, bool) {
+	if b, ok := value.(BoolValue); ok {
+		return b.value, true
+	}
+	if isNumeric(value) {
+		return value.GetFloat() != 0, true
+	}
+	return false, false
+}
+
 // String
 func (s StringValue) GetString() string {
 	return s.value
@@ -105,25 +511,7 @@ func (s StringValue) GetSequence() []CoreValue {
 
 // Sequence
 func (s SequenceValue) GetString() string {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("[%d]:", len(s.value)))
-	for _, v := range s.value {
-		if v.GetType() == InstructionType {
-			sb.WriteString("%")
-
-		} else if v.GetType() == SequenceType {
-			sb.WriteString(fmt.Sprintf("[%d]", len(v.GetSequence())))
-
-		} else {
-			sb.WriteString(v.GetString())
-		}
-		sb.WriteByte(',')
-		if sb.Len() > 40 {
-			sb.WriteString("...")
-			break
-		}
-	}
-	return sb.String()
+	return literalString(s)
 }
 
 func (s SequenceValue) GetType() CoreValueType {
@@ -134,6 +522,109 @@ func (s SequenceValue) GetSequence() []CoreValue {
 	return s.value
 }
 
+// Map
+func NewMapValue(keys []CoreValue, values []CoreValue) MapValue {
+	m := MapValue{index: map[string]int{}}
+	for i := range keys {
+		m = m.Put(keys[i], values[i])
+	}
+	return m
+}
+
+func (m MapValue) GetString() string {
+	return literalString(m)
+}
+
+func (m MapValue) GetType() CoreValueType {
+	return MapType
+}
+
+func (m MapValue) GetSequence() []CoreValue {
+	return []CoreValue{m}
+}
+
+func (m MapValue) Len() int {
+	return len(m.keys)
+}
+
+func (m MapValue) Keys() []CoreValue {
+	return append([]CoreValue{}, m.keys...)
+}
+
+func (m MapValue) Values() []CoreValue {
+	return append([]CoreValue{}, m.values...)
+}
+
+func (m MapValue) Get(key CoreValue) (CoreValue, bool) {
+	i, ok := m.index[mapKey(key)]
+	if !ok {
+		return DefaultValue{}, false
+	}
+	return m.values[i], true
+}
+
+// Create a copy of the map with key set to value
+func (m MapValue) Put(key CoreValue, value CoreValue) MapValue {
+	result := m.copy()
+	k := mapKey(key)
+	if i, ok := result.index[k]; ok {
+		result.values[i] = value
+		return result
+	}
+	result.index[k] = len(result.keys)
+	result.keys = append(result.keys, key)
+	result.values = append(result.values, value)
+	return result
+}
+
+// Create a copy of the map without key
+func (m MapValue) Delete(key CoreValue) MapValue {
+	result := MapValue{index: map[string]int{}}
+	k := mapKey(key)
+	for i := range m.keys {
+		if mapKey(m.keys[i]) != k {
+			result.index[mapKey(m.keys[i])] = len(result.keys)
+			result.keys = append(result.keys, m.keys[i])
+			result.values = append(result.values, m.values[i])
+		}
+	}
+	return result
+}
+
+func (m MapValue) copy() MapValue {
+	result := MapValue{
+		keys:   append([]CoreValue{}, m.keys...),
+		values: append([]CoreValue{}, m.values...),
+		index:  make(map[string]int, len(m.index)),
+	}
+	for k, i := range m.index {
+		result.index[k] = i
+	}
+	return result
+}
+
+// Identify a key by its type and full value, treating equal numbers as the same key
+func mapKey(key CoreValue) string {
+	switch key.GetType() {
+	case FloatType, IntegerType:
+		return "n:" + strconv.FormatFloat(key.GetFloat(), 'g', -1, 64)
+	case SequenceType:
+		parts := make([]string, len(key.GetSequence()))
+		for i, value := range key.GetSequence() {
+			parts[i] = mapKey(value)
+		}
+		return "s:[" + strings.Join(parts, ",") + "]"
+	case MapType:
+		m := key.(MapValue)
+		parts := make([]string, len(m.keys))
+		for i := range m.keys {
+			parts[i] = mapKey(m.keys[i]) + "=" + mapKey(m.values[i])
+		}
+		return "m:{" + strings.Join(parts, ",") + "}"
+	}
+	return fmt.Sprintf("%d:%s", key.GetType(), key.GetString())
+}
+
 // Instruction
 func (s InstructionValue) GetString() string {
 	return s.value.description
@@ -172,13 +663,21 @@ func (r ReferenceValue) Dereference(core *Core) CoreValue {
 	if result.GetType() != DefaultType {
 		return result
 	}
-	variable, ok := Variables[r.value]
+	if locals := core.localScope(r.value); locals != nil {
+		return locals[r.value]
+	}
+	variable, ok := core.variable(r.value)
 	if ok {
+		if sequence, isSequence := variable.(SequenceValue); isSequence && sequence.name == "" {
+			sequence.name = r.value
+			return sequence
+		}
 		return variable
 	}
-	variable, ok = Programs[r.value]
+	program, ok := core.env.Program(r.value)
 	if ok {
-		return variable
+		program.name = r.value
+		return program
 	}
 	Logger.Printf("Error: Failed to dreference reference: value=%s\n", r.value)
 	return DefaultValue{}
@@ -190,8 +689,47 @@ func (r ReferenceValue) GetString() string {
 
 func (r ReferenceValue) DereferenceRegister(core *Core) CoreValue {
 	switch r.value {
-	case "loopCounter":
+	case "loopCounter", Reg_LoopC:
 		return FloatValue{value: float64(core.Regs.LoopCounter)}
 	}
 	return DefaultValue{}
 }
+
+// Error
+func (e ErrorValue) GetType() CoreValueType {
+	return ErrorType
+}
+
+func (e ErrorValue) GetString() string {
+	return e.message
+}
+
+func (e ErrorValue) GetFloat() float64 {
+	return float64(e.code)
+}
+
+func (e ErrorValue) GetInt() int {
+	return int(e.code)
+}
+
+func (e ErrorValue) GetSequence() []CoreValue {
+	return []CoreValue{e}
+}
+
+func (e ErrorValue) Code() ErrorCode {
+	return e.code
+}
+
+func (e ErrorValue) Message() string {
+	return e.message
+}
+
+// The instruction that raised the error, if any
+func (e ErrorValue) Instruction() string {
+	return e.instruction
+}
+
+// The frames being evaluated when the error was raised, innermost first
+func (e ErrorValue) Trace() []string {
+	return e.trace
+}
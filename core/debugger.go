@@ -0,0 +1,235 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	Continue DebugAction = 1
+	Step                 = 2
+	StepOver             = 3
+	StepOut              = 4
+)
+
+type (
+	DebugAction int8
+	// A sequence being evaluated, where Position indexes the value being evaluated
+	Frame struct {
+		Name     string
+		Sequence []CoreValue
+		Position int
+		// Local variables bound for the sequence, if any
+		Locals map[string]CoreValue
+	}
+	Debugger struct {
+		// Called on the evaluating goroutine when paused, returning once a resuming command has run
+		Pause       func(*Core)
+		breakpoints map[string]bool
+		action      DebugAction
+		depth       int
+		paused      bool
+		lock        sync.Mutex
+	}
+)
+
+var debugHelp = "(s)tep, (n)ext, (o)ut, (c)ontinue, (b)reak <name>, clear <name>, breaks, (w)here, (l)ist, stacks"
+
+func NewDebugger(pause func(*Core)) *Debugger {
+	return &Debugger{Pause: pause, breakpoints: map[string]bool{}, action: Continue}
+}
+
+// Pause before value if a breakpoint or step condition is met
+func (d *Debugger) check(c *Core, value CoreValue) {
+	depth := len(c.frames)
+	frame := c.frames[depth-1]
+
+	d.lock.Lock()
+	pause := false
+	switch d.action {
+	case Step:
+		pause = true
+	case StepOver:
+		pause = depth <= d.depth
+	case StepOut:
+		pause = depth < d.depth
+	}
+	if frame.Position == len(frame.Sequence)-1 && d.breakpoints[frame.Name] {
+		pause = true
+	}
+	if instruction, ok := value.(InstructionValue); ok && d.breakpoints[instruction.name] {
+		pause = true
+	}
+	if !pause {
+		d.lock.Unlock()
+		return
+	}
+	d.action = Continue
+	d.depth = depth
+	d.paused = true
+	d.lock.Unlock()
+
+	Logger.Printf("Debugger paused: frame=%s, position=%d\n", frame.Name, frame.Position)
+	d.Pause(c)
+
+	d.lock.Lock()
+	d.paused = false
+	d.lock.Unlock()
+}
+
+func (d *Debugger) Paused() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.paused
+}
+
+// Execute a debugger command, returning true if evaluation should resume
+func (d *Debugger) Execute(c *Core, input string) (bool, []string) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false, []string{debugHelp}
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	switch fields[0] {
+	case "s", "step":
+		d.action = Step
+		return true, nil
+	case "n", "next":
+		d.action = StepOver
+		return true, nil
+	case "o", "out":
+		d.action = StepOut
+		return true, nil
+	case "c", "continue":
+		d.action = Continue
+		return true, nil
+	case "b", "break":
+		if len(fields) != 2 {
+			return false, []string{"Usage: break <program or instruction>"}
+		}
+		d.breakpoints[fields[1]] = true
+		return false, []string{"Breakpoint set: " + fields[1]}
+	case "clear":
+		if len(fields) != 2 {
+			return false, []string{"Usage: clear <program or instruction>"}
+		}
+		delete(d.breakpoints, fields[1])
+		return false, []string{"Breakpoint cleared: " + fields[1]}
+	case "breaks":
+		names := []string{}
+		for name := range d.breakpoints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return false, []string{"Breakpoints: " + strings.Join(names, ", ")}
+	}
+
+	if !d.paused {
+		return false, []string{"Not paused"}
+	}
+	switch fields[0] {
+	case "w", "where":
+		return false, d.Where(c)
+	case "l", "list":
+		return false, d.Listing(c, -1)
+	case "stacks":
+		return false, d.Stacks(c)
+	}
+	return false, []string{"Unknown debug command: " + fields[0], debugHelp}
+}
+
+// Describe each frame being evaluated, innermost first
+func (d *Debugger) Where(c *Core) []string {
+	lines := []string{}
+	for i := len(c.frames) - 1; i >= 0; i-- {
+		lines = append(lines, fmt.Sprintf("#%d %s", i, c.frames[i].String()))
+		if len(c.frames[i].Locals) != 0 {
+			names := make([]string, 0, len(c.frames[i].Locals))
+			for name := range c.frames[i].Locals {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for j, name := range names {
+				names[j] = name + "=" + c.frames[i].Locals[name].GetString()
+			}
+			lines = append(lines, "   locals: "+strings.Join(names, " "))
+		}
+	}
+	return lines
+}
+
+// Describe the frame name, the step being evaluated and its value
+func (f Frame) String() string {
+	name := f.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	step := len(f.Sequence) - 1 - f.Position
+	return fmt.Sprintf("%s [%d/%d] %s", name, step, len(f.Sequence), sourceString(f.Sequence[f.Position]))
+}
+
+// List the innermost sequence in evaluation order, marking the active value, and
+// limiting the output to a window of size lines when size is positive
+func (d *Debugger) Listing(c *Core, size int) []string {
+	if len(c.frames) == 0 {
+		return []string{}
+	}
+	frame := c.frames[len(c.frames)-1]
+	count := len(frame.Sequence)
+	active := count - 1 - frame.Position
+	start, end := 0, count
+	if size > 0 && count > size {
+		start = active - size/2
+		if start < 0 {
+			start = 0
+		}
+		end = start + size
+		if end > count {
+			end = count
+			start = end - size
+		}
+	}
+	lines := []string{}
+	for step := start; step < end; step++ {
+		marker := "  "
+		if step == active {
+			marker = "->"
+		}
+		lines = append(lines, fmt.Sprintf("%s %3d: %s", marker, step, sourceString(frame.Sequence[count-1-step])))
+	}
+	return lines
+}
+
+// Describe every stack, with the current stack first
+func (d *Debugger) Stacks(c *Core) []string {
+	lines := []string{}
+	stacks := c.stackStack.ToArray()
+	for i, stack := range stacks {
+		values := stack.ToArray()
+		strs := make([]string, len(values))
+		for j, value := range values {
+			strs[j] = value.GetString()
+		}
+		lines = append(lines, fmt.Sprintf("[%d] %s", len(stacks)-1-i, strings.Join(strs, ", ")))
+	}
+	return lines
+}
+
+// Describe a value as it would appear in a program
+func sourceString(value CoreValue) string {
+	switch v := value.(type) {
+	case InstructionValue:
+		return v.name
+	case ReferenceValue:
+		return "$" + v.value
+	case StringValue:
+		return "'" + v.value
+	case SequenceValue:
+		return fmt.Sprintf("<[%d]>", len(v.value))
+	}
+	return value.GetString()
+}
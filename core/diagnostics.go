@@ -0,0 +1,44 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A problem found while loading input, with the line and column where it starts,
+// counting from 1. File is empty for input that is not from a file
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Token   string
+	Message string
+}
+
+func (d Diagnostic) Error() string {
+	message := d.Message
+	if d.Token != "" {
+		message += ": " + d.Token
+	}
+	if d.File == "" {
+		return fmt.Sprintf("line %d, column %d: %s", d.Line, d.Column, message)
+	}
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, message)
+}
+
+// Attribute the diagnostics, and any diagnostic held by err, to a file
+func inFile(file string, diagnostics []Diagnostic, err error) []Diagnostic {
+	var d Diagnostic
+	if errors.As(err, &d) {
+		diagnostics = append(diagnostics, d)
+	} else if err != nil {
+		diagnostics = append(diagnostics, Diagnostic{Message: err.Error()})
+	}
+	for i := range diagnostics {
+		diagnostics[i].File = file
+	}
+	return diagnostics
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// How floats are shown, like the HP-28 STD, FIX, SCI and ENG modes
+type DisplayMode int8
+
+const (
+	StdDisplay DisplayMode = iota
+	FixDisplay
+	SciDisplay
+	EngDisplay
+)
+
+// Prefixes of integer literals in each base, with decimal integers shown without one
+var basePrefixes = map[int]string{2: "0b", 8: "0o", 10: "", 16: "#"}
+
+// Mask an integer to the word size, leaving 64 bit integers signed
+func (c *Core) wrap(value CoreValue) CoreValue {
+	if i, ok := value.(IntegerValue); ok && c.Regs.WordSize < 64 {
+		return IntegerValue{value: i.value & (1<<c.Regs.WordSize - 1)}
+	}
+	return value
+}
+
+// Describe a value as it is shown on the stack and by print, following the display modes,
+// which also apply to the elements of arrays, sequences and maps. The value itself keeps
+// its full precision
+func (c *Core) Format(value CoreValue) string {
+	switch v := value.(type) {
+	case IntegerValue:
+		return formatInteger(v.value, c.Regs.Base, c.Regs.WordSize)
+	case FloatValue:
+		return c.formatReal(v.value)
+	case ComplexValue:
+		return "(" + c.formatReal(real(v.value)) + "," + c.formatReal(imag(v.value)) + ")"
+	case QuantityValue:
+		return c.formatReal(v.value) + "_" + v.units.String()
+	case ArrayValue:
+		return v.describe(c.formatReal)
+	case SequenceValue, MapValue:
+		return describeLiteral(value, c.Format)
+	}
+	return value.GetString()
+}
+
+// Describe a matrix with one line per row and aligned columns, as on the stack panel,
+// showing 6 significant digits in standard mode
+func (c *Core) FormatLines(a ArrayValue) []string {
+	if c.Regs.Display == StdDisplay {
+		return a.lines(func(value float64) string {
+			return strconv.FormatFloat(value, 'g', 6, 64)
+		})
+	}
+	return a.lines(c.formatReal)
+}
+
+func (c *Core) formatReal(value float64) string {
+	return formatFloat(value, c.Regs.Display, c.Regs.Digits)
+}
+
+// Describe a float with a number of digits after the decimal point, or in standard
+// mode with as many digits as are needed
+func formatFloat(value float64, mode DisplayMode, digits int) string {
+	switch mode {
+	case FixDisplay:
+		return strconv.FormatFloat(value, 'f', digits, 64)
+	case SciDisplay:
+		return strconv.FormatFloat(value, 'E', digits, 64)
+	case EngDisplay:
+		return formatEngineering(value, digits)
+	}
+	return strconv.FormatFloat(value, 'G', 12, 64)
+}
+
+// Describe a float with digits+1 significant digits and an exponent that is a multiple of 3
+func formatEngineering(value float64, digits int) string {
+	if value == 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'E', digits, 64)
+	}
+	// Round first, since rounding can carry into the next power of ten
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'E', digits, 64), 64)
+	exponent := int(math.Floor(math.Log10(math.Abs(rounded))))
+	engineering := exponent - ((exponent%3)+3)%3
+	mantissa := rounded / math.Pow10(engineering)
+	return fmt.Sprintf("%sE%+03d", strconv.FormatFloat(mantissa, 'f', max(digits-(exponent-engineering), 0), 64), engineering)
+}
+
+// Describe an integer in a base, showing negative numbers as their two's complement
+// in the word size unless the base is 10
+func formatInteger(value int64, base int, wordSize int) string {
+	if base == 10 || base == 0 {
+		return strconv.FormatInt(value, 10)
+	}
+	bits := uint64(value)
+	if wordSize < 64 {
+		bits &= 1<<wordSize - 1
+	}
+	return basePrefixes[base] + strconv.FormatUint(bits, base)
+}
@@ -0,0 +1,63 @@
+package core
+
+// Environment holds the state owned by a single core, layered over a shared ROM
+type Environment struct {
+	rom             *Rom
+	variables       map[string]CoreValue
+	currentSequence []CoreValue
+	// Modules by program name, and the modules being imported, innermost last
+	modules   map[string]*module
+	importing []*module
+}
+
+func NewEnvironment(rom *Rom) *Environment {
+	return &Environment{
+		rom:             rom,
+		variables:       make(map[string]CoreValue),
+		currentSequence: []CoreValue{},
+		modules:         make(map[string]*module),
+	}
+}
+
+// Look up a variable, falling back to the ROM constants. While a module is being
+// imported, its own definitions are found first
+func (e *Environment) Variable(name string) (CoreValue, bool) {
+	if len(e.importing) != 0 {
+		if value, ok := e.importing[len(e.importing)-1].definitions[name]; ok {
+			return value, true
+		}
+	}
+	value, ok := e.variables[name]
+	if ok {
+		return value, true
+	}
+	value, ok = e.rom.constants[name]
+	return value, ok
+}
+
+// Set a variable, or a definition of the module being imported
+func (e *Environment) SetVariable(name string, value CoreValue) {
+	if len(e.importing) != 0 {
+		e.importing[len(e.importing)-1].definitions[name] = value
+		return
+	}
+	e.variables[name] = value
+}
+
+// Remove a variable, revealing any ROM constant of the same name
+func (e *Environment) Purge(name string) {
+	if len(e.importing) != 0 {
+		delete(e.importing[len(e.importing)-1].definitions, name)
+		return
+	}
+	delete(e.variables, name)
+}
+
+func (e *Environment) Program(name string) (SequenceValue, bool) {
+	value, ok := e.rom.Programs[name]
+	return value, ok
+}
+
+func (e *Environment) Rom() *Rom {
+	return e.rom
+}
@@ -0,0 +1,76 @@
+package core
+
+import "fmt"
+
+const (
+	ErrInvalidInput       ErrorCode = 1
+	ErrInvalidInstruction           = 2
+	ErrTooFewArguments              = 3
+	ErrInstructionFailed            = 4
+	ErrInterrupted                  = 5
+	ErrThrown                       = 6
+	ErrInstructionQuota             = 7
+	ErrStackQuota                   = 8
+	ErrStackDepthQuota              = 9
+	ErrValueQuota                   = 10
+	ErrVariableQuota                = 11
+	ErrDeadline                     = 12
+	ErrFrameQuota                   = 13
+)
+
+type ErrorCode int
+
+// The frames kept at each end of the trace of an error raised in a deep recursion
+const traceEnds = 10
+
+// Raise an error, aborting the evaluation in progress until it is caught
+func (c *Core) raise(code ErrorCode, message string) {
+	trace := make([]string, 0, min(len(c.frames), 2*traceEnds+1))
+	for i := len(c.frames) - 1; i >= 0; i-- {
+		if skipped := len(c.frames) - 2*traceEnds; skipped > 1 && i == len(c.frames)-1-traceEnds {
+			trace = append(trace, fmt.Sprintf("... %d more frames", skipped))
+			i -= skipped - 1
+			continue
+		}
+		trace = append(trace, c.frames[i].String())
+	}
+	c.raiseValue(ErrorValue{code: code, message: message, instruction: c.instruction, trace: trace})
+}
+
+func (c *Core) raiseValue(err ErrorValue) {
+	Logger.Printf("Error raised: code=%d, message=%s, instruction=%s, trace=%s\n", err.code, err.message, err.instruction, err.trace)
+	c.Error = err
+	c.LastError = err
+	c.Regs.Mode = Halted
+}
+
+// Interrupts and exceeded quotas abort the whole evaluation
+func (e ErrorValue) catchable() bool {
+	return e.code != ErrInterrupted && e.code < ErrInstructionQuota
+}
+
+func (c *Core) unsetError() {
+	c.Error = DefaultValue{}
+}
+
+// Check if an error has been raised and not yet caught
+func (c *Core) failed() bool {
+	return c.Error.GetType() == ErrorType
+}
+
+// Evaluate x, returning an error raised by it after unwinding the stacks it pushed
+func (c *Core) evalCatching(x CoreValue) (ErrorValue, bool) {
+	depth := c.stackStack.length
+	mode := c.Regs.Mode
+	c.EvalValue(x)
+	err, ok := c.Error.(ErrorValue)
+	if !ok || !err.catchable() {
+		return ErrorValue{}, false
+	}
+	for c.stackStack.length > depth {
+		c.DropStack()
+	}
+	c.unsetError()
+	c.Regs.Mode = mode
+	return err, true
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Digits carried by decimals unless changed with prec, as in IEEE 754 decimal128
+const DefaultPrecision = 34
+
+func precisionBits(digits int) uint {
+	return uint(math.Ceil(float64(digits) * math.Log2(10)))
+}
+
+// Parse a fraction of two integers such as 1/3 or -22/7
+func parseRational(input string) (*big.Rat, bool) {
+	numerator, denominator, ok := strings.Cut(input, "/")
+	if !ok {
+		return nil, false
+	}
+	n, nOk := new(big.Int).SetString(numerator, 10)
+	d, dOk := new(big.Int).SetString(denominator, 10)
+	if !nOk || !dOk || d.Sign() <= 0 {
+		return nil, false
+	}
+	return new(big.Rat).SetFrac(n, d), true
+}
+
+// Parse a decimal such as 1.5d, carrying at least as many digits as were input
+func parseDecimal(input string) (DecimalValue, bool) {
+	number, ok := strings.CutSuffix(input, "d")
+	if !ok || number == "" || !strings.ContainsAny(number[:1], "0123456789.-+") {
+		return DecimalValue{}, false
+	}
+	digits := 0
+	for _, r := range strings.ToLower(number) {
+		if r == 'e' {
+			break
+		}
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	digits = max(digits, DefaultPrecision)
+	value, _, err := big.ParseFloat(number, 10, precisionBits(digits), big.ToNearestEven)
+	if err != nil || value.IsInf() {
+		return DecimalValue{}, false
+	}
+	return DecimalValue{value: value, digits: digits}, true
+}
+
+func isExact(value CoreValue) bool {
+	return value.GetType() == RationalType || value.GetType() == DecimalType
+}
+
+// Get an integer or rational as a fraction
+func ratOf(value CoreValue) *big.Rat {
+	switch v := value.(type) {
+	case RationalValue:
+		return v.value
+	case IntegerValue:
+		return new(big.Rat).SetInt64(v.value)
+	}
+	r, _ := new(big.Float).SetFloat64(value.GetFloat()).Rat(nil)
+	return r
+}
+
+// Get a number as a decimal with the given number of bits of precision
+func bigFloatOf(value CoreValue, bits uint) *big.Float {
+	z := new(big.Float).SetPrec(bits)
+	switch v := value.(type) {
+	case DecimalValue:
+		return z.Set(v.value)
+	case RationalValue:
+		return z.SetRat(v.value)
+	case IntegerValue:
+		return z.SetInt64(v.value)
+	}
+	// Take floats as written, so that 0.1 is exactly a tenth
+	z.SetString(strconv.FormatFloat(value.GetFloat(), 'g', -1, 64))
+	return z
+}
+
+// Get the sign of a number, without rounding small rationals and decimals to zero
+func signOf(value CoreValue) int {
+	switch v := value.(type) {
+	case RationalValue:
+		return v.value.Sign()
+	case DecimalValue:
+		return v.value.Sign()
+	}
+	switch f := value.GetFloat(); {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+// Apply an operation to two numbers when either is a rational or a decimal. The result
+// is a decimal if either operand is, a float if the other is a float, and otherwise a rational
+func exactArithmetic(core *Core, x CoreValue, y CoreValue, ratOp func(z, y, x *big.Rat) *big.Rat, decimalOp func(z, y, x *big.Float) *big.Float) (CoreValue, bool) {
+	if !isExact(x) && !isExact(y) || !isNumeric(x) || !isNumeric(y) {
+		return DefaultValue{}, false
+	}
+	if x.GetType() == DecimalType || y.GetType() == DecimalType {
+		bits := precisionBits(core.Regs.Precision)
+		z := new(big.Float).SetPrec(bits)
+		return DecimalValue{value: decimalOp(z, bigFloatOf(y, bits), bigFloatOf(x, bits)), digits: core.Regs.Precision}, true
+	}
+	if x.GetType() == FloatType || y.GetType() == FloatType {
+		return DefaultValue{}, false
+	}
+	return newRational(ratOp(new(big.Rat), ratOf(y), ratOf(x))), true
+}
+
+// Set z to y modulus x, as y - x*trunc(y/x), so the result has the sign of y like the
+// integer and float modulus
+func ratModulus(z, y, x *big.Rat) *big.Rat {
+	quotient := new(big.Rat).Quo(y, x)
+	truncated := new(big.Int).Quo(quotient.Num(), quotient.Denom())
+	return z.Sub(y, z.Mul(x, new(big.Rat).SetInt(truncated)))
+}
+
+// Set z to y modulus x, as y - x*trunc(y/x)
+func decimalModulus(z, y, x *big.Float) *big.Float {
+	quotient := new(big.Float).SetPrec(z.Prec()).Quo(y, x)
+	truncated, _ := quotient.Int(nil)
+	product := new(big.Float).SetPrec(z.Prec()).SetInt(truncated)
+	return z.Sub(y, product.Mul(product, x))
+}
+
+// Find the simplest fraction within a relative tolerance of x, using continued fractions
+func fraction(x *big.Rat, tolerance *big.Rat) *big.Rat {
+	h0, h1 := big.NewInt(0), big.NewInt(1)
+	k0, k1 := big.NewInt(1), big.NewInt(0)
+	limit := new(big.Rat).Abs(x)
+	limit.Mul(limit, tolerance)
+	remainder := new(big.Rat).Set(x)
+	result := new(big.Rat)
+	for i := 0; i < 100; i++ {
+		a := new(big.Int).Div(remainder.Num(), remainder.Denom())
+		h2 := new(big.Int).Add(new(big.Int).Mul(a, h1), h0)
+		k2 := new(big.Int).Add(new(big.Int).Mul(a, k1), k0)
+		result.SetFrac(h2, k2)
+		difference := new(big.Rat).Sub(result, x)
+		if difference.Abs(difference).Cmp(limit) <= 0 {
+			break
+		}
+		remainder.Sub(remainder, new(big.Rat).SetInt(a))
+		if remainder.Sign() == 0 {
+			break
+		}
+		remainder.Inv(remainder)
+		h0, h1 = h1, h2
+		k0, k1 = k1, k2
+	}
+	return result
+}
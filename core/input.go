@@ -5,12 +5,20 @@ import (
 	"strings"
 )
 
-func RawToCoreValue(input string) CoreValue {
+func (r *Rom) RawToCoreValue(input string) CoreValue {
 	value := RawToImmediateCoreValue(input)
 	if value.GetType() != DefaultType {
 		return value
 	}
-	return RawToInstruction(input)
+	return r.RawToInstruction(input)
+}
+
+// Convert a token, taking a double quoted token as a string
+func (r *Rom) TokenToCoreValue(token Token) CoreValue {
+	if token.Quoted {
+		return StringValue{value: token.Text}
+	}
+	return r.RawToCoreValue(token.Text)
 }
 
 func RawToImmediateCoreValue(input string) CoreValue {
@@ -18,25 +26,72 @@ func RawToImmediateCoreValue(input string) CoreValue {
 		return DefaultValue{}
 	}
 
+	switch input {
+	case "true":
+		return BoolValue{value: true}
+	case "false":
+		return BoolValue{value: false}
+	}
+
 	if len(input) > 1 {
 		switch input[0] {
 		case '\'':
-			input = strings.TrimPrefix(input, "'")
-			return StringValue{value: input}
+			if text, ok := algebraicText(input); ok {
+				algebraic, err := NewAlgebraicValue(text)
+				if err != nil {
+					return DefaultValue{}
+				}
+				return algebraic
+			}
+			return StringValue{value: strings.TrimPrefix(input, "'")}
 
 		case '$':
 			input = strings.TrimPrefix(input, "$")
 			return ReferenceValue{value: input}
 
-		case '[':
-			input = strings.TrimPrefix(input, "[")
-			input = strings.TrimSuffix(input, "]")
-			entries := strings.Split(input, ",")
-			values := make([]CoreValue, len(entries))
-			for i, entry := range entries {
-				values[i] = RawToImmediateCoreValue(entry)
+		case '[', '{':
+			value, err := parseLiteral(input)
+			if err != nil {
+				return DefaultValue{}
 			}
-			return SequenceValue{value: values}
+			return value
+
+		case '(':
+			input = strings.TrimPrefix(input, "(")
+			input = strings.TrimSuffix(input, ")")
+			re, im, ok := strings.Cut(input, ",")
+			if !ok {
+				return DefaultValue{}
+			}
+			reValue, reErr := strconv.ParseFloat(strings.TrimSpace(re), 64)
+			imValue, imErr := strconv.ParseFloat(strings.TrimSpace(im), 64)
+			if reErr != nil || imErr != nil {
+				return DefaultValue{}
+			}
+			return ComplexValue{value: complex(reValue, imValue)}
+
+		case '|':
+			input = strings.TrimPrefix(input, "|")
+			input = strings.TrimSuffix(input, "|")
+			rows := [][]float64{}
+			for _, row := range strings.Split(input, ";") {
+				values := []float64{}
+				for _, entry := range strings.Split(row, ",") {
+					value, err := strconv.ParseFloat(strings.TrimSpace(entry), 64)
+					if err != nil {
+						return DefaultValue{}
+					}
+					values = append(values, value)
+				}
+				if len(rows) > 0 && len(values) != len(rows[0]) {
+					return DefaultValue{}
+				}
+				rows = append(rows, values)
+			}
+			if len(rows) == 1 {
+				return NewVectorValue(rows[0])
+			}
+			return NewMatrixValue(rows)
 
 		case '%':
 			input = strings.TrimPrefix(input, "%")
@@ -45,6 +100,30 @@ func RawToImmediateCoreValue(input string) CoreValue {
 		}
 	}
 
+	if number, units, ok := strings.Cut(input, "_"); ok {
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return DefaultValue{}
+		}
+		q, err := NewQuantityValue(value, units)
+		if err != nil {
+			return DefaultValue{}
+		}
+		return q
+	}
+
+	if rational, ok := parseRational(input); ok {
+		return newRational(rational)
+	}
+
+	if decimal, ok := parseDecimal(input); ok {
+		return decimal
+	}
+
+	if integer, ok := parseInteger(input); ok {
+		return IntegerValue{value: integer}
+	}
+
 	result, err := strconv.ParseFloat(input, 64)
 	if err == nil {
 		return FloatValue{value: result}
@@ -53,15 +132,59 @@ func RawToImmediateCoreValue(input string) CoreValue {
 	return DefaultValue{}
 }
 
-func RawToInstruction(input string) CoreValue {
+// Parse a hexadecimal integer such as #ff or 0xff, an octal integer such as 0o17,
+// a binary integer such as 0b1010, or a decimal integer such as 0d42
+// Get the expression within quotes, such as x^2 from 'x^2', when input is quoted at both ends
+func algebraicText(input string) (string, bool) {
+	if len(input) > 2 && strings.HasPrefix(input, "'") && strings.HasSuffix(input, "'") {
+		return input[1 : len(input)-1], true
+	}
+	return "", false
+}
+
+func parseInteger(input string) (int64, bool) {
+	sign := int64(1)
+	digits := input
+	if strings.HasPrefix(digits, "-") {
+		sign = -1
+		digits = digits[1:]
+	}
+	base := 0
+	switch {
+	case strings.HasPrefix(digits, "#"):
+		base = 16
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "0x"):
+		base = 16
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0o"):
+		base = 8
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0d"):
+		base = 10
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0b"):
+		base = 2
+		digits = digits[2:]
+	default:
+		return 0, false
+	}
+	result, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return 0, false
+	}
+	return sign * int64(result), true
+}
+
+func (r *Rom) RawToInstruction(input string) CoreValue {
 	Logger.Printf("Parsing raw to core: input=%s\n", input)
 	if input == "" {
 		return DefaultValue{}
 	}
 
-	instruction, ok := instructionMap[input]
+	instruction, ok := r.instructions[input]
 	if ok {
-		return InstructionValue{value: instruction}
+		return InstructionValue{value: instruction, name: input}
 	}
 
 	return DefaultValue{}
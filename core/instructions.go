@@ -25,81 +25,145 @@ func (i *Instruction) IsValid() bool {
 	return i.description != ""
 }
 
-var instructionMap = map[string]Instruction{
-	"+":        {"Add x and y", 2, 1, add, "6 ⤶ 2 ⤶ + ⤶ ⤒8"},
-	"v+":       {"Addition for all permuations of x and y", 2, 1, vplus, ""},
-	"-":        {"Subtract x from y", 2, 1, subtract, "6 ⤶ 2 ⤶ - ⤶ ⤒4"},
-	"*":        {"Multiply y by x", 2, 1, multiply, "6 ⤶ 2 ⤶ * ⤶ ⤒12"},
-	"/":        {"Divide y by x", 2, 1, divide, "6 ⤶ 2 ⤶ / ⤶ ⤒3"},
-	"mod":      {"y modulus by x", 2, 1, modulus, "6 ⤶ 2 ⤶ / ⤶ ⤒0"},
-	"inverse":  {"Inverts x", 1, 1, inverse, ""},
-	"sin":      {"sin of x", 1, 1, sin, ""},
-	"cos":      {"cos of x", 1, 1, cos, ""},
-	"rand":     {"Generate random between 0 and 1", 0, 1, random, ""},
-	"<":        {"Define sequence", 0, 0, defineSequence, "< ⤶"},
-	">":        {"Define sequence", 0, 0, reduceSequence, "> ⤶"},
-	"this":     {"Refer to the current sequence", 0, 1, this, "this ⤶"},
-	"eval":     {"Evaluate x", 1, 0, nil, ""},
-	"consume":  {"Pop from previous stack and push to current", 0, 1, consume, "consume ⤶"},
-	"produce":  {"Pop from this stack and push to previous", 1, 0, produce, "produce ⤶"},
-	"apply":    {"Evalue x against all entries in y to modify y", 2, 1, apply, "apply ⤶"},
-	"each":     {"Evaluate x against all entries in y", 2, 0, each, ""},
-	"reduce":   {"Use x to reduce y to a single value", 2, 1, reduce, "reduce ⤶"},
-	"enter":    {"Enter function, creating a new stack", 0, 0, enter, "enter ⤶"},
-	"end":      {"Return from function, dropping the stack", 0, 0, end, "end ⤶"},
-	"store":    {"Store y into x, preserving y", 2, 1, store, "2 ⤶ 'a ⤶ put ⤶ ⤒2; y⥗a"},
-	"move":     {"Store y into x", 2, 0, move, "2 ⤶ 'a ⤶ asref ⤶ y⥗a"},
-	"exchange": {"Exchange y and the value in var x", 2, 1, exchange, "3 ⤶ 'a ⤶ exchange ⤶ ⤒a 3⥗a"},
-	"get":      {"Dereference x, preserving x", 1, 1, get, "'a ⤶ get ⤶ ⤒a"},
-	"deref":    {"Derefernce x, replacing x", 1, 1, deref, "'a ⤶ deref ⤶ ⤒a"},
-	"purge":    {"Deallocate that reference x", 1, 0, purge, "'a ⤶ purge ⤶ undefined⥗a"},
-	"drop":     {"Drop x", 1, 0, drop, "drop ⤶"},
-	"swap":     {"Swap x and y", 2, 2, swap, "swap ⤶ ⤒x,y"},
-	"clear":    {"Clear stack", 0, 0, clear, "clear ⤶"},
-	"collect":  {"Collect stack into x", 1, 1, collect, "1 ⤶ 2 ⤶ collect ⤶ ⤒[2]:1,2"},
-	"pair":     {"Collect x and y into x", 2, 1, pair, "1 ⤶ 2 ⤶ collect ⤶ ⤒[2]:1,2"},
-	"expand":   {"Expand x into the stack", 1, -1, expand, "⤒[2]:1,2 | expand ⤶ ⤒1 ⤒2"},
-	"dup":      {"Duplicates x on the stack", 1, 2, duplicate, ""},
-	"print":    {"Print x", 1, 0, print, "'Hello world ⤶ print ⤶ Hello world⥱Console"},
-	"clearbuf": {"Clear the output buffer", 0, 0, clearBuffer, ""},
-	"render":   {"Render RAM as buffer", 0, 0, render, "render ⤶"},
-	"show":     {"Render and pause", 0, 0, show, ""},
-	"graph":    {"Graph a sequence", 3, 0, graph, "graph ⤶"},
-	"prompt":   {"Prompt the user for a value", 1, 1, prompt, "'Enter x ⤶ prompt ⤶"},
-	"status":   {"Display status", 0, 0, nil, ""},
-	"files":    {"List availabel files in ROM", 0, 0, files, "files ⤶ [files]⥱Console"},
-	"mmap":     {"Map a file to RAM", 1, 0, mmap, "'rom/file.raw ⤶ mmap ⤶ file.byes⥱RAM"},
-	"stream":   {"Apply x to renderable RAM", 1, 0, stream, ""},
-	"zero":     {"Zero RAM", 0, 0, zero, ""},
-	"repeat":   {"Execute x repeatedly", 1, 0, repeat, "0 ⤶ < ⤶'f ⤶ repeat ⤶"},
-	"<=":       {"Set the result flag to 1 if y <= x", 2, 0, lessThan, ""},
-	">=":       {"Set the result flag to 1 if y >= x", 2, 0, greaterThan, ""},
-	"==":       {"Set the result flag to 1 if x = y", 2, 0, equals, ""},
-	"!=":       {"Set the result flag to 1 if x != y", 2, 0, notEquals, ""},
-	"unset":    {"Sets the result flat to 0", 0, 0, unset, ""},
-	"ceval":    {"Conditionally evaluate x if result flag is 1", 1, 0, ceval, "⤒<sequence> | ceval ⤶"},
-	"ceval2":   {"Conditionally evaluate y if result flag is 1, otherwise evaluate x", 2, 0, ceval2, "⤒<sequence>, ⤒<sequence> | ceval2 ⤶"},
-	"generate": {"Evaluate a pair where y is the last input and x is the generator", 1, 1, generate, "⤒<pair> ⤶ generate ⤶ ⤒<pair>, ⤒<result>"},
-	"setloop":  {"Set loop counter to x", 1, 0, setLoop, "5 ⤶ setloop ⤶"},
-	"dec":      {"Decrement the loop register", 0, 0, decrement, "dec"},
-	"loop":     {"Execute x if the loop counter is not zero", 0, 0, loopNotZero, "5 ⤶ setloop ⤶ ⤒<sequence> | loop ⤶"},
-	"halt":     {"Halt execution", 0, 0, halt, "halt ⤶"},
-	"sleep":    {"Sleep for x ms", 1, 0, sleep, ""},
-	"inspect":  {"Write a raw object to file", 1, 0, inspect, ""},
-	"stop":     {"Stop the current loop", 0, 0, stop, ""},
-}
-
-var currentSequence = []CoreValue{}
-
-func InitializeInstructionMap() {
-	value := instructionMap["eval"]
-	value.impl = eval
-	instructionMap["eval"] = value
+func newInstructionMap() map[string]Instruction {
+	return map[string]Instruction{
+		"+":        {"Add x and y", 2, 1, add, "6 ⤶ 2 ⤶ + ⤶ ⤒8"},
+		"v+":       {"Addition for all permuations of x and y", 2, 1, vplus, ""},
+		"-":        {"Subtract x from y", 2, 1, subtract, "6 ⤶ 2 ⤶ - ⤶ ⤒4"},
+		"*":        {"Multiply y by x", 2, 1, multiply, "6 ⤶ 2 ⤶ * ⤶ ⤒12"},
+		"/":        {"Divide y by x", 2, 1, divide, "6 ⤶ 2 ⤶ / ⤶ ⤒3"},
+		"mod":      {"y modulus by x", 2, 1, modulus, "6 ⤶ 2 ⤶ / ⤶ ⤒0"},
+		"inverse":  {"Inverts x, including square matrices", 1, 1, inverse, ""},
+		"sin":      {"sin of x", 1, 1, sin, ""},
+		"cos":      {"cos of x", 1, 1, cos, ""},
+		"rand":     {"Generate random between 0 and 1", 0, 1, random, ""},
+		"<":        {"Define sequence", 0, 0, defineSequence, "< ⤶"},
+		">":        {"Define sequence", 0, 0, reduceSequence, "> ⤶"},
+		"this":     {"Refer to the current sequence", 0, 1, this, "this ⤶"},
+		"eval":     {"Evaluate x", 1, 0, eval, ""},
+		"consume":  {"Pop from previous stack and push to current", 0, 1, consume, "consume ⤶"},
+		"produce":  {"Pop from this stack and push to previous", 1, 0, produce, "produce ⤶"},
+		"apply":    {"Evalue x against all entries in y to modify y, where y is a sequence or the values of a map", 2, 1, apply, "apply ⤶"},
+		"each":     {"Evaluate x against all entries in y, pushing the index or key and the value", 2, 0, each, ""},
+		"reduce":   {"Use x to reduce y to a single value", 2, 1, reduce, "reduce ⤶"},
+		"enter":    {"Enter function, creating a new stack", 0, 0, enter, "enter ⤶"},
+		"end":      {"Return from function, dropping the stack", 0, 0, end, "end ⤶"},
+		"store":    {"Store y into x, preserving y", 2, 1, store, "2 ⤶ 'a ⤶ put ⤶ ⤒2; y⥗a"},
+		"move":     {"Store y into x", 2, 0, move, "2 ⤶ 'a ⤶ asref ⤶ y⥗a"},
+		"exchange": {"Exchange y and the value in var x", 2, 1, exchange, "3 ⤶ 'a ⤶ exchange ⤶ ⤒a 3⥗a"},
+		"get":      {"Dereference x, preserving x", 1, 1, get, "'a ⤶ get ⤶ ⤒a"},
+		"deref":    {"Derefernce x, replacing x", 1, 1, deref, "'a ⤶ deref ⤶ ⤒a"},
+		"purge":    {"Deallocate that reference x", 1, 0, purge, "'a ⤶ purge ⤶ undefined⥗a"},
+		"drop":     {"Drop x", 1, 0, drop, "drop ⤶"},
+		"swap":     {"Swap x and y", 2, 2, swap, "swap ⤶ ⤒x,y"},
+		"clear":    {"Clear stack", 0, 0, clear, "clear ⤶"},
+		"collect":  {"Collect stack into x, or collect like terms of an expression", 1, 1, collect, "1 ⤶ 2 ⤶ collect ⤶ ⤒[2]:1,2"},
+		"pair":     {"Collect x and y into x", 2, 1, pair, "1 ⤶ 2 ⤶ collect ⤶ ⤒[2]:1,2"},
+		"expand":   {"Expand x into the stack, or expand the products and powers of an expression", 1, -1, expand, "⤒[2]:1,2 | expand ⤶ ⤒1 ⤒2"},
+		"dup":      {"Duplicates x on the stack", 1, 2, duplicate, ""},
+		"print":    {"Print x", 1, 0, print, "'Hello world ⤶ print ⤶ Hello world⥱Console"},
+		"clearbuf": {"Clear the output buffer", 0, 0, clearBuffer, ""},
+		"render":   {"Render RAM as buffer", 0, 0, render, "render ⤶"},
+		"show":     {"Render and pause", 0, 0, show, ""},
+		"graph":    {"Graph a sequence", 3, 0, graph, "graph ⤶"},
+		"prompt":   {"Prompt the user for a value", 1, 1, prompt, "'Enter x ⤶ prompt ⤶"},
+		"status":   {"Display status", 0, 0, nil, ""},
+		"files":    {"List availabel files in ROM", 0, 0, files, "files ⤶ [files]⥱Console"},
+		"mmap":     {"Map a file to RAM", 1, 0, mmap, "'rom/file.raw ⤶ mmap ⤶ file.byes⥱RAM"},
+		"stream":   {"Apply x to renderable RAM", 1, 0, stream, ""},
+		"zero":     {"Zero RAM", 0, 0, zero, ""},
+		"repeat":   {"Execute x repeatedly", 1, 0, repeat, "0 ⤶ < ⤶'f ⤶ repeat ⤶"},
+		"<=":       {"Push true if x <= y, or set the result flag in flag mode", 2, 1, lessThan, "2 ⤶ 1 ⤶ <= ⤶ ⤒true"},
+		">=":       {"Push true if x >= y, or set the result flag in flag mode", 2, 1, greaterThan, "1 ⤶ 2 ⤶ >= ⤶ ⤒true"},
+		"==":       {"Push true if x = y, or set the result flag in flag mode", 2, 1, equals, "1 ⤶ 1 ⤶ == ⤶ ⤒true"},
+		"!=":       {"Push true if x != y, or set the result flag in flag mode", 2, 1, notEquals, "1 ⤶ 2 ⤶ != ⤶ ⤒true"},
+		"unset":    {"Sets the result flat to 0", 0, 0, unset, ""},
+		"ceval":    {"Conditionally evaluate x if the boolean beneath x, or the result flag in flag mode, is true", 1, 0, ceval, "⤒<sequence> | ceval ⤶"},
+		"flags":    {"Make comparisons set the result flag instead of pushing a boolean", 0, 0, setFlagMode(true), "flags ⤶ 1 ⤶ 2 ⤶ <= ⤶"},
+		"bools":    {"Make comparisons push a boolean, which is the default", 0, 0, setFlagMode(false), "bools ⤶ 1 ⤶ 2 ⤶ <= ⤶ ⤒false"},
+		"ceval2":   {"Conditionally evaluate y if the boolean beneath y, or the result flag in flag mode, is true, otherwise evaluate x", 2, 0, ceval2, "⤒<sequence>, ⤒<sequence> | ceval2 ⤶"},
+		"generate": {"Evaluate a pair where y is the last input and x is the generator", 1, 1, generate, "⤒<pair> ⤶ generate ⤶ ⤒<pair>, ⤒<result>"},
+		"setloop":  {"Set loop counter to x", 1, 0, setLoop, "5 ⤶ setloop ⤶"},
+		"dec":      {"Decrement the loop register", 0, 0, decrement, "dec"},
+		"loop":     {"Execute x if the loop counter is not zero", 0, 0, loopNotZero, "5 ⤶ setloop ⤶ ⤒<sequence> | loop ⤶"},
+		"halt":     {"Halt execution", 0, 0, halt, "halt ⤶"},
+		"sleep":    {"Sleep for x ms", 1, 0, sleep, ""},
+		"inspect":  {"Write a raw object to file", 1, 0, inspect, ""},
+		"stop":     {"Stop the current loop", 0, 0, stop, ""},
+		"save":     {"Save the state of the VM to file x", 1, 0, save, "'state.json ⤶ save ⤶ VM⥱state.json"},
+		"restore":  {"Restore the state of the VM from file x", 1, 0, restore, "'state.json ⤶ restore ⤶ state.json⥱VM"},
+		"try":      {"Evaluate y, evaluating x with the error pushed if y fails", 2, 0, try, "⤒<sequence>, ⤒<sequence> | try ⤶"},
+		"catch":    {"Evaluate x, pushing the error if x fails or an empty value", 1, 1, catch, "⤒<sequence> | catch ⤶ ⤒error"},
+		"throw":    {"Raise x as an error", 1, 0, throw, "'Out of range ⤶ throw ⤶"},
+		"errn":     {"Push the code of the last error", 0, 1, errorNumber, "errn ⤶ ⤒3"},
+		"errm":     {"Push the message of the last error", 0, 1, errorMessage, "errm ⤶ ⤒Too few arguments"},
+		"div":      {"Integer division of y by x", 2, 1, integerDivide, "7 ⤶ 2 ⤶ div ⤶ ⤒3"},
+		"and":      {"Logical and of booleans, or bitwise and of numbers, y and x", 2, 1, and, "#f0 ⤶ #3c ⤶ and ⤶ ⤒48"},
+		"or":       {"Logical or of booleans, or bitwise or of numbers, y and x", 2, 1, or, "true ⤶ false ⤶ or ⤶ ⤒true"},
+		"xor":      {"Logical exclusive or of booleans, or bitwise exclusive or of numbers, y and x", 2, 1, xor, "#ff ⤶ 0b1010 ⤶ xor ⤶ ⤒245"},
+		"not":      {"Logical negation of a boolean, or bitwise complement of a number, x", 1, 1, not, "true ⤶ not ⤶ ⤒false"},
+		"shl":      {"Shift y left by x bits", 2, 1, shiftLeft, "1 ⤶ 4 ⤶ shl ⤶ ⤒16"},
+		"shr":      {"Shift y right by x bits", 2, 1, shiftRight, "#80 ⤶ 7 ⤶ shr ⤶ ⤒1"},
+		"if":       {"Evaluate x if y is true", 2, 0, ifThen, "true ⤶ ⤒<sequence> | if ⤶"},
+		"mget":     {"Get the value for key x from map y", 2, 1, mapGet, "{'pc:23} ⤶ 'pc ⤶ mget ⤶ ⤒23"},
+		"mput":     {"Set key y to value x in map z", 3, 1, mapPut, "{'pc:23} ⤶ 'pc ⤶ 24 ⤶ mput ⤶ ⤒{'pc:24}"},
+		"mdel":     {"Delete key x from map y", 2, 1, mapDelete, "{'pc:23} ⤶ 'pc ⤶ mdel ⤶ ⤒{}"},
+		"mkeys":    {"List the keys of map x", 1, 1, mapKeys, "{'pc:23} ⤶ mkeys ⤶ ⤒[1]:pc"},
+		"mhas":     {"Check if map y has key x", 2, 1, mapHas, "{'pc:23} ⤶ 'pc ⤶ mhas ⤶ ⤒true"},
+		"re":       {"Real part of x", 1, 1, realPart, "(3,4) ⤶ re ⤶ ⤒3"},
+		"im":       {"Imaginary part of x", 1, 1, imaginaryPart, "(3,4) ⤶ im ⤶ ⤒4"},
+		"abs":      {"Absolute value, or magnitude, of x", 1, 1, absolute, "(3,4) ⤶ abs ⤶ ⤒5"},
+		"arg":      {"Angle of x in radians", 1, 1, argument, "(0,1) ⤶ arg ⤶ ⤒1.5707963267948966"},
+		"conj":     {"Complex conjugate of x", 1, 1, conjugate, "(3,4) ⤶ conj ⤶ ⤒(3,-4)"},
+		"r->c":     {"Combine real y and imaginary x into a complex number", 2, 1, realToComplex, "3 ⤶ 4 ⤶ r->c ⤶ ⤒(3,4)"},
+		"c->r":     {"Split x into real and imaginary parts", 1, 2, complexToReal, "(3,4) ⤶ c->r ⤶ ⤒3 ⤒4"},
+		"r->p":     {"Convert x from rectangular to polar form (r,θ)", 1, 1, rectangularToPolar, "(0,1) ⤶ r->p ⤶ ⤒(1,1.5707963267948966)"},
+		"p->r":     {"Convert x from polar form (r,θ) to rectangular form", 1, 1, polarToRectangular, "(2,0) ⤶ p->r ⤶ ⤒(2,0)"},
+		".*":       {"Multiply the elements of arrays y and x", 2, 1, elementwiseMultiply, "|1,2| ⤶ |3,4| ⤶ .* ⤶ ⤒|3,8|"},
+		"trn":      {"Transpose matrix x", 1, 1, transpose, "|1,2;3,4| ⤶ trn ⤶ ⤒|1,3;2,4|"},
+		"det":      {"Determinant of square matrix x", 1, 1, determinant, "|1,2;3,4| ⤶ det ⤶ ⤒-2"},
+		"solve":    {"Solve x·a = y for a, where x is a square matrix", 2, 1, solve, "|5,6| ⤶ |1,2;3,4| ⤶ solve ⤶ ⤒|-4,4.5|"},
+		"dot":      {"Dot product of vectors y and x", 2, 1, dot, "|1,2,3| ⤶ |4,5,6| ⤶ dot ⤶ ⤒32"},
+		"cross":    {"Cross product of vectors y and x", 2, 1, cross, "|1,0,0| ⤶ |0,1,0| ⤶ cross ⤶ ⤒|0,0,1|"},
+		"idn":      {"Identity matrix of size x", 1, 1, identity, "2 ⤶ idn ⤶ ⤒|1,0;0,1|"},
+		"con":      {"Array filled with x, with y elements or [rows,cols]", 2, 1, constant, "[2,3] ⤶ 0 ⤶ con ⤶ ⤒|0,0,0;0,0,0|"},
+		"convert":  {"Convert quantity y to the units of x", 2, 1, convert, "1_mi ⤶ 'km ⤶ convert ⤶ ⤒1.609344_km"},
+		"ubase":    {"Convert quantity x to SI base units", 1, 1, unitBase, "1_N ⤶ ubase ⤶ ⤒1_kg*m/s^2"},
+		"uval":     {"Remove the units from quantity x", 1, 1, unitValue, "9.8_m/s^2 ⤶ uval ⤶ ⤒9.8"},
+		"^":        {"Raise y to the power of x", 2, 1, power, "2 ⤶ 10 ⤶ ^ ⤶ ⤒1024"},
+		"neg":      {"Negate x", 1, 1, negate, "3 ⤶ neg ⤶ ⤒-3"},
+		"exp":      {"e raised to the power of x", 1, 1, exponential, ""},
+		"ln":       {"Natural logarithm of x", 1, 1, naturalLog, ""},
+		"sqrt":     {"Square root of x", 1, 1, squareRoot, "9 ⤶ sqrt ⤶ ⤒3"},
+		"deriv":    {"Differentiate expression y with respect to variable x", 2, 1, derivative, "'x^2+3*x' ⤶ 'x ⤶ deriv ⤶ ⤒'2*x+3'"},
+		"subst":    {"Substitute x for variable y in expression z", 3, 1, substitute, "'x^2' ⤶ 'x ⤶ 'y+1' ⤶ subst ⤶ ⤒'(y+1)^2'"},
+		"->rpn":    {"Convert expression x to a sequence, taking its only variable from the stack", 1, 1, algebraicToRPN, "'x^2' ⤶ ->rpn ⤶ ⤒[^,2,$x,move,'x]"},
+		"->q":      {"Convert x to a fraction", 1, 1, toFraction, "0.75 ⤶ ->q ⤶ ⤒3/4"},
+		"prec":     {"Set the digits carried by decimals to x", 1, 0, precision, "50 ⤶ prec ⤶"},
+		"HEX":      {"Show integers in hexadecimal", 0, 0, setBase(16), "0d255 ⤶ HEX ⤶ ⤒#ff"},
+		"DEC":      {"Show integers in decimal", 0, 0, setBase(10), "#ff ⤶ DEC ⤶ ⤒255"},
+		"OCT":      {"Show integers in octal", 0, 0, setBase(8), "#ff ⤶ OCT ⤶ ⤒0o377"},
+		"BIN":      {"Show integers in binary", 0, 0, setBase(2), "#5 ⤶ BIN ⤶ ⤒0b101"},
+		"STWS":     {"Set the word size integers are wrapped to, 8, 16, 32 or 64 bits", 1, 0, setWordSize, "8 ⤶ STWS ⤶ #ff ⤶ #1 ⤶ + ⤶ ⤒#0"},
+		"r->b":     {"Convert x to an integer, truncating a float", 1, 1, realToBinary, "128 ⤶ r->b ⤶ HEX ⤶ ⤒#80"},
+		"b->r":     {"Convert integer x to a float", 1, 1, binaryToReal, "#80 ⤶ b->r ⤶ ⤒128"},
+		"RCWS":     {"Recall the word size", 0, 1, recallWordSize, "RCWS ⤶ ⤒64"},
+		"std":      {"Show floats with up to 12 significant digits", 0, 0, setDisplay(StdDisplay), "std ⤶ 2 ⤶ ⤒2"},
+		"fix":      {"Show floats with x digits after the decimal point", 1, 0, setDisplay(FixDisplay), "2 ⤶ fix ⤶ 3.14159 ⤶ ⤒3.14"},
+		"sci":      {"Show floats in scientific notation with x digits after the decimal point", 1, 0, setDisplay(SciDisplay), "2 ⤶ sci ⤶ 1234 ⤶ ⤒1.23E+03"},
+		"eng":      {"Show floats in engineering notation with x+1 significant digits", 1, 0, setDisplay(EngDisplay), "2 ⤶ eng ⤶ 12345 ⤶ ⤒12.3E+03"},
+		"->":       {"Bind the values beneath y to the local names in y and evaluate x", 2, 0, bindLocals, "1 ⤶ 2 ⤶ ['a,'b] ⤶ < $a $b - > ⤶ -> ⤶ ⤒-1"},
+		"import":   {"Import program x once as a module, publishing its definitions as variables named after it", 1, 0, importProgram, "'tests/game/player ⤶ import ⤶ player.fDrawPc⥗"},
+		"export":   {"Make only the definitions named in x visible outside of the module being imported", 1, 0, export, "['fDrawPc,'fSetPc] ⤶ export ⤶"},
+		"ifelse":   {"Evaluate y if z is true, otherwise evaluate x", 3, 0, ifThenElse, "true ⤶ ⤒<sequence>, ⤒<sequence> | ifelse ⤶"},
+		// Problems found while loading the ROM
+		"romerrors": {"List the problems found in ROM files that could not be loaded", 0, 0, romErrors, "romerrors ⤶ [errors]⥱Console"},
+	}
 }
 
 func OutputInstructionHelpDoc() {
 	fmt.Printf("## Supported instructions\n\n")
-	for k, v := range instructionMap {
+	for k, v := range newInstructionMap() {
 		fmt.Printf("### %s\n- Description: %s\n- Arg count: %d\n- Result count: %d\n- Usage: %s\n\n", k, v.description, v.argCount, v.resultCount, v.usage)
 	}
 }
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"math/cmplx"
+)
+
+// Get the expression held by an algebraic value, or a constant expression for a number
+func expressionOf(value CoreValue) (*expr, bool) {
+	switch v := value.(type) {
+	case AlgebraicValue:
+		return v.value, true
+	case FloatValue, IntegerValue:
+		return number(v.GetFloat()), true
+	}
+	return nil, false
+}
+
+// Push an expression, as a float when it no longer refers to any variables. A constant
+// expression evaluates as the stack arithmetic would, so 1/0 is +Inf
+func pushExpression(core *Core, e *expr) {
+	e = e.simplify()
+	if value, ok := e.evaluate(nil); ok {
+		core.Push(FloatValue{value: value})
+		return
+	}
+	core.Push(AlgebraicValue{value: e})
+}
+
+// Combine y and x, at least one of which is an expression, into an expression applying op
+func algebraicArithmetic(core *Core, x CoreValue, y CoreValue, op string) InstructionResult {
+	ye, yOk := expressionOf(y)
+	xe, xOk := expressionOf(x)
+	if !yOk || !xOk {
+		return InstructionResult{true, "Unexpected operands"}
+	}
+	pushExpression(core, binary(op, ye, xe))
+	return successResult
+}
+
+// Substitute the numeric local and global variables, then simplify
+func (c *Core) evalAlgebraic(a AlgebraicValue) {
+	e := a.value
+	for _, name := range e.variables(nil) {
+		if value := (ReferenceValue{value: name}).Dereference(c); isNumeric(value) {
+			e = e.substitute(name, number(value.GetFloat()))
+		}
+	}
+	pushExpression(c, e)
+}
+
+func derivative(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	e, ok := expressionOf(y)
+	if !ok || x.GetType() != StringType {
+		return InstructionResult{true, "Expected an expression and a variable name"}
+	}
+	pushExpression(core, e.derivative(x.GetString()))
+	return successResult
+}
+
+func substitute(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	z := consumeOne(core)
+	e, ok := expressionOf(z)
+	value, valueOk := expressionOf(x)
+	if !ok || !valueOk || y.GetType() != StringType {
+		return InstructionResult{true, "Expected an expression, a variable name and a value"}
+	}
+	pushExpression(core, e.substitute(y.GetString(), value))
+	return successResult
+}
+
+func expandAlgebraic(core *Core) InstructionResult {
+	a := consumeOne(core).(AlgebraicValue)
+	pushExpression(core, a.value.simplify().expand().collect())
+	return successResult
+}
+
+func collectAlgebraic(core *Core) InstructionResult {
+	a := consumeOne(core).(AlgebraicValue)
+	pushExpression(core, a.value.collect())
+	return successResult
+}
+
+// Convert an expression to an RPN sequence. An expression of a single variable
+// takes its value from the stack, so the sequence can be passed to graph
+func toRPN(core *Core, a AlgebraicValue) SequenceValue {
+	values := []CoreValue{}
+	if names := a.value.variables(nil); len(names) == 1 {
+		values = append(values, StringValue{value: names[0]}, core.env.rom.RawToInstruction("move"))
+	}
+	values = a.value.rpn(values, core.env.rom)
+	sequence := make([]CoreValue, len(values))
+	for i, value := range values {
+		sequence[len(values)-i-1] = value
+	}
+	return SequenceValue{value: sequence}
+}
+
+func algebraicToRPN(core *Core) InstructionResult {
+	a, ok := consumeOne(core).(AlgebraicValue)
+	if !ok {
+		return InstructionResult{true, "Expected an expression"}
+	}
+	core.Push(toRPN(core, a))
+	return successResult
+}
+
+func power(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	if x.GetType() == AlgebraicType || y.GetType() == AlgebraicType {
+		return algebraicArithmetic(core, x, y, "^")
+	}
+	if q, ok := y.(QuantityValue); ok {
+		return quantityPower(core, q, x)
+	}
+	if (x.GetType() == ComplexType || y.GetType() == ComplexType) && isComplex(x) && isComplex(y) {
+		core.Push(ComplexValue{value: cmplx.Pow(complexOf(y), complexOf(x))})
+		return successResult
+	}
+	if !isNumeric(x) || !isNumeric(y) {
+		return InstructionResult{true, "Unexpected operands"}
+	}
+	core.Push(FloatValue{value: math.Pow(y.GetFloat(), x.GetFloat())})
+	return successResult
+}
+
+func negate(core *Core) InstructionResult {
+	x := consumeOne(core)
+	switch v := x.(type) {
+	case IntegerValue:
+		core.Push(IntegerValue{value: -v.value})
+	case ComplexValue:
+		core.Push(ComplexValue{value: -v.value})
+	case RationalValue:
+		core.Push(RationalValue{value: new(big.Rat).Neg(v.value)})
+	case DecimalValue:
+		core.Push(DecimalValue{value: new(big.Float).Neg(v.value), digits: v.digits})
+	case AlgebraicValue:
+		pushExpression(core, &expr{op: "neg", operands: []*expr{v.value}})
+	default:
+		if !isNumeric(x) {
+			return InstructionResult{true, "Unexpected operand"}
+		}
+		core.Push(FloatValue{value: -x.GetFloat()})
+	}
+	return successResult
+}
+
+// Apply a real function to x, or its complex counterpart when x is complex
+func realOrComplex(core *Core, real func(float64) float64, complex func(complex128) complex128) InstructionResult {
+	x := consumeOne(core)
+	if c, ok := x.(ComplexValue); ok {
+		core.Push(ComplexValue{value: complex(c.value)})
+		return successResult
+	}
+	if !isNumeric(x) {
+		return InstructionResult{true, "Unexpected operand"}
+	}
+	core.Push(FloatValue{value: real(x.GetFloat())})
+	return successResult
+}
+
+func exponential(core *Core) InstructionResult {
+	return realOrComplex(core, math.Exp, cmplx.Exp)
+}
+
+func naturalLog(core *Core) InstructionResult {
+	return realOrComplex(core, math.Log, cmplx.Log)
+}
+
+func squareRoot(core *Core) InstructionResult {
+	if q, ok := (*core.currentStack().Peek()).(QuantityValue); ok {
+		consumeOne(core)
+		return quantityRoot(core, q)
+	}
+	if d, ok := (*core.currentStack().Peek()).(DecimalValue); ok && d.value.Sign() >= 0 {
+		consumeOne(core)
+		core.Push(DecimalValue{value: new(big.Float).SetPrec(d.value.Prec()).Sqrt(d.value), digits: d.digits})
+		return successResult
+	}
+	return realOrComplex(core, math.Sqrt, cmplx.Sqrt)
+}
@@ -0,0 +1,306 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// The most cells idn and con may allocate when no value quota is set
+const maxArrayCells = 1 << 24
+
+// Check a requested array size before allocating it, within the value quota if one is set
+func checkArraySize(core *Core, rows int, cols int) InstructionResult {
+	limit := maxArrayCells
+	if quota := core.Quotas.MaxValueBytes; quota > 0 && quota/8 < limit {
+		limit = quota / 8
+	}
+	if rows > limit/cols {
+		return InstructionResult{true, fmt.Sprintf("Array of %d×%d exceeds the limit of %d elements", rows, cols, limit)}
+	}
+	return successResult
+}
+
+// Apply an operation to the elements of two arrays of the same shape
+func arrayElementwise(core *Core, x CoreValue, y CoreValue, op func(y, x float64) float64) InstructionResult {
+	a, aOk := y.(ArrayValue)
+	b, bOk := x.(ArrayValue)
+	if !aOk || !bOk {
+		return InstructionResult{true, "Expected two arrays"}
+	}
+	if a.rows != b.rows || a.cols != b.cols || a.vector != b.vector {
+		return InstructionResult{true, "Array dimensions do not match"}
+	}
+	result := a
+	result.data = make([]float64, len(a.data))
+	for i := range a.data {
+		result.data[i] = op(a.data[i], b.data[i])
+	}
+	core.Push(result)
+	return successResult
+}
+
+// Apply an operation between each element of an array and a number
+func arrayScale(core *Core, x CoreValue, y CoreValue, op func(y, x float64) float64) InstructionResult {
+	a, arrayIsY := y.(ArrayValue)
+	scalar := x
+	if !arrayIsY {
+		a = x.(ArrayValue)
+		scalar = y
+	}
+	if !isNumeric(scalar) {
+		return InstructionResult{true, "Unexpected operands"}
+	}
+	result := a
+	result.data = make([]float64, len(a.data))
+	for i, value := range a.data {
+		if arrayIsY {
+			result.data[i] = op(value, scalar.GetFloat())
+		} else {
+			result.data[i] = op(scalar.GetFloat(), value)
+		}
+	}
+	core.Push(result)
+	return successResult
+}
+
+// Treat a vector as a column when it is the right operand of a product
+func asColumn(a ArrayValue) ArrayValue {
+	if a.vector {
+		return ArrayValue{rows: a.cols, cols: 1, data: a.data}
+	}
+	return a
+}
+
+func matrixProduct(core *Core, x ArrayValue, y ArrayValue) InstructionResult {
+	b := asColumn(x)
+	if y.cols != b.rows {
+		return InstructionResult{true, "Array dimensions do not match"}
+	}
+	result := newMatrix(y.rows, b.cols)
+	for i := 0; i < y.rows; i++ {
+		for j := 0; j < b.cols; j++ {
+			sum := 0.0
+			for k := 0; k < y.cols; k++ {
+				sum += y.At(i, k) * b.At(k, j)
+			}
+			result.data[i*result.cols+j] = sum
+		}
+	}
+	if x.vector || y.vector {
+		result = NewVectorValue(result.data)
+	}
+	core.Push(result)
+	return successResult
+}
+
+func elementwiseMultiply(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	return arrayElementwise(core, x, y, func(y, x float64) float64 { return y * x })
+}
+
+func transpose(core *Core) InstructionResult {
+	a, ok := consumeOne(core).(ArrayValue)
+	if !ok {
+		return InstructionResult{true, "Expected an array"}
+	}
+	if a.vector {
+		core.Push(a)
+		return successResult
+	}
+	result := newMatrix(a.cols, a.rows)
+	for i := 0; i < a.rows; i++ {
+		for j := 0; j < a.cols; j++ {
+			result.data[j*result.cols+i] = a.At(i, j)
+		}
+	}
+	core.Push(result)
+	return successResult
+}
+
+// Reduce a copy of a square matrix to upper triangular form with partial pivoting,
+// applying the same row operations to a copy of b, and returning the determinant
+func eliminate(a ArrayValue, b ArrayValue) (ArrayValue, ArrayValue, float64) {
+	a = ArrayValue{rows: a.rows, cols: a.cols, data: append([]float64{}, a.data...)}
+	b = ArrayValue{rows: b.rows, cols: b.cols, vector: b.vector, data: append([]float64{}, b.data...)}
+	n := a.rows
+	det := 1.0
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a.At(row, col)) > math.Abs(a.At(pivot, col)) {
+				pivot = row
+			}
+		}
+		if a.At(pivot, col) == 0 {
+			return a, b, 0
+		}
+		if pivot != col {
+			swapRows(a, pivot, col)
+			swapRows(b, pivot, col)
+			det = -det
+		}
+		det *= a.At(col, col)
+		for row := col + 1; row < n; row++ {
+			factor := a.At(row, col) / a.At(col, col)
+			for k := col; k < n; k++ {
+				a.data[row*a.cols+k] -= factor * a.At(col, k)
+			}
+			for k := 0; k < b.cols; k++ {
+				b.data[row*b.cols+k] -= factor * b.At(col, k)
+			}
+		}
+	}
+	return a, b, det
+}
+
+func swapRows(a ArrayValue, i int, j int) {
+	for k := 0; k < a.cols; k++ {
+		a.data[i*a.cols+k], a.data[j*a.cols+k] = a.data[j*a.cols+k], a.data[i*a.cols+k]
+	}
+}
+
+// Solve a·x = b for x, where b is a matrix or a column vector
+func solveSystem(a ArrayValue, b ArrayValue) (ArrayValue, InstructionResult) {
+	if a.vector || a.rows != a.cols {
+		return ArrayValue{}, InstructionResult{true, "Expected a square matrix"}
+	}
+	column := asColumn(b)
+	if column.rows != a.rows {
+		return ArrayValue{}, InstructionResult{true, "Array dimensions do not match"}
+	}
+	upper, result, det := eliminate(a, column)
+	if det == 0 {
+		return ArrayValue{}, InstructionResult{true, "Singular matrix"}
+	}
+	n := a.rows
+	for row := n - 1; row >= 0; row-- {
+		for k := 0; k < result.cols; k++ {
+			sum := result.At(row, k)
+			for j := row + 1; j < n; j++ {
+				sum -= upper.At(row, j) * result.At(j, k)
+			}
+			result.data[row*result.cols+k] = sum / upper.At(row, row)
+		}
+	}
+	if b.vector {
+		return NewVectorValue(result.data), successResult
+	}
+	return result, successResult
+}
+
+func determinant(core *Core) InstructionResult {
+	a, ok := consumeOne(core).(ArrayValue)
+	if !ok || a.vector || a.rows != a.cols {
+		return InstructionResult{true, "Expected a square matrix"}
+	}
+	_, _, det := eliminate(a, newMatrix(a.rows, 0))
+	core.Push(FloatValue{value: det})
+	return successResult
+}
+
+func matrixInverse(core *Core, a ArrayValue) InstructionResult {
+	identity := newMatrix(a.rows, a.rows)
+	for i := 0; i < a.rows; i++ {
+		identity.data[i*a.rows+i] = 1
+	}
+	result, status := solveSystem(a, identity)
+	if status.error {
+		return status
+	}
+	core.Push(result)
+	return successResult
+}
+
+func solve(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	a, aOk := x.(ArrayValue)
+	b, bOk := y.(ArrayValue)
+	if !aOk || !bOk {
+		return InstructionResult{true, "Expected two arrays"}
+	}
+	result, status := solveSystem(a, b)
+	if status.error {
+		return status
+	}
+	core.Push(result)
+	return successResult
+}
+
+func dot(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	a, aOk := y.(ArrayValue)
+	b, bOk := x.(ArrayValue)
+	if !aOk || !bOk || !a.vector || !b.vector {
+		return InstructionResult{true, "Expected two vectors"}
+	}
+	if len(a.data) != len(b.data) {
+		return InstructionResult{true, "Array dimensions do not match"}
+	}
+	sum := 0.0
+	for i := range a.data {
+		sum += a.data[i] * b.data[i]
+	}
+	core.Push(FloatValue{value: sum})
+	return successResult
+}
+
+func cross(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	a, aOk := y.(ArrayValue)
+	b, bOk := x.(ArrayValue)
+	if !aOk || !bOk || !a.vector || !b.vector || len(a.data) != 3 || len(b.data) != 3 {
+		return InstructionResult{true, "Expected two vectors of length 3"}
+	}
+	core.Push(NewVectorValue([]float64{
+		a.data[1]*b.data[2] - a.data[2]*b.data[1],
+		a.data[2]*b.data[0] - a.data[0]*b.data[2],
+		a.data[0]*b.data[1] - a.data[1]*b.data[0],
+	}))
+	return successResult
+}
+
+func identity(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if !isNumeric(x) || x.GetInt() < 1 {
+		return InstructionResult{true, "Expected a positive size"}
+	}
+	n := x.GetInt()
+	if sized := checkArraySize(core, n, n); sized.error {
+		return sized
+	}
+	result := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		result.data[i*n+i] = 1
+	}
+	core.Push(result)
+	return successResult
+}
+
+// Create an array filled with x, where y is a vector length or a sequence of rows and columns
+func constant(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	if !isNumeric(x) {
+		return InstructionResult{true, "Expected a number"}
+	}
+	var result ArrayValue
+	dims := y.GetSequence()
+	switch {
+	case isNumeric(y) && y.GetInt() > 0:
+		if sized := checkArraySize(core, 1, y.GetInt()); sized.error {
+			return sized
+		}
+		result = NewVectorValue(make([]float64, y.GetInt()))
+	case y.GetType() == SequenceType && len(dims) == 2 && dims[0].GetInt() > 0 && dims[1].GetInt() > 0:
+		if sized := checkArraySize(core, dims[0].GetInt(), dims[1].GetInt()); sized.error {
+			return sized
+		}
+		result = newMatrix(dims[0].GetInt(), dims[1].GetInt())
+	default:
+		return InstructionResult{true, "Expected a size or a sequence of rows and columns"}
+	}
+	for i := range result.data {
+		result.data[i] = x.GetFloat()
+	}
+	core.Push(result)
+	return successResult
+}
@@ -0,0 +1,104 @@
+package core
+
+// Apply a logical operation to two booleans, or a bitwise operation to two numbers,
+// truncating floats to integers
+func bitwise(core *Core, logical func(y, x bool) bool, op func(y, x int64) int64) InstructionResult {
+	x, y := consumeTwo(core)
+	if x.GetType() == BoolType && y.GetType() == BoolType {
+		core.Push(BoolValue{value: logical(y.(BoolValue).value, x.(BoolValue).value)})
+		return successResult
+	}
+	if !isNumeric(x) || !isNumeric(y) {
+		return InstructionResult{true, "Unexpected operands"}
+	}
+	core.Push(IntegerValue{value: op(integerOf(y), integerOf(x))})
+	return successResult
+}
+
+func and(core *Core) InstructionResult {
+	return bitwise(core, func(y, x bool) bool { return y && x }, func(y, x int64) int64 { return y & x })
+}
+
+func or(core *Core) InstructionResult {
+	return bitwise(core, func(y, x bool) bool { return y || x }, func(y, x int64) int64 { return y | x })
+}
+
+func xor(core *Core) InstructionResult {
+	return bitwise(core, func(y, x bool) bool { return y != x }, func(y, x int64) int64 { return y ^ x })
+}
+
+func not(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if b, ok := x.(BoolValue); ok {
+		core.Push(BoolValue{value: !b.value})
+		return successResult
+	}
+	if !isNumeric(x) {
+		return InstructionResult{true, "Unexpected operand"}
+	}
+	core.Push(IntegerValue{value: ^integerOf(x)})
+	return successResult
+}
+
+func shiftLeft(core *Core) InstructionResult {
+	return shift(core, func(y int64, x uint) int64 { return y << x })
+}
+
+func shiftRight(core *Core) InstructionResult {
+	return shift(core, func(y int64, x uint) int64 { return y >> x })
+}
+
+// Shift y by x bits
+func shift(core *Core, op func(y int64, x uint) int64) InstructionResult {
+	x, y := consumeTwo(core)
+	if !isNumeric(x) || !isNumeric(y) {
+		return InstructionResult{true, "Unexpected operands"}
+	}
+	if integerOf(x) < 0 {
+		return InstructionResult{true, "Negative shift"}
+	}
+	core.Push(IntegerValue{value: op(integerOf(y), uint(integerOf(x)))})
+	return successResult
+}
+
+// Show integers in a base
+func setBase(base int) InstructionImpl {
+	return func(core *Core) InstructionResult {
+		core.Regs.Base = base
+		return successResult
+	}
+}
+
+// Set the number of bits integers are wrapped to
+func setWordSize(core *Core) InstructionResult {
+	x := consumeOne(core)
+	switch integerOf(x) {
+	case 8, 16, 32, 64:
+		core.Regs.WordSize = int(integerOf(x))
+		return successResult
+	}
+	return InstructionResult{true, "Expected a word size of 8, 16, 32 or 64"}
+}
+
+func recallWordSize(core *Core) InstructionResult {
+	core.Push(IntegerValue{value: int64(core.Regs.WordSize)})
+	return successResult
+}
+
+func realToBinary(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if !isNumeric(x) {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(IntegerValue{value: integerOf(x)})
+	return successResult
+}
+
+func binaryToReal(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if !isNumeric(x) {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(FloatValue{value: x.GetFloat()})
+	return successResult
+}
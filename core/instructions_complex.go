@@ -0,0 +1,109 @@
+package core
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Consume a real or complex number
+func consumeComplex(core *Core) (complex128, bool) {
+	x := consumeOne(core)
+	if !isComplex(x) {
+		return 0, false
+	}
+	return complexOf(x), true
+}
+
+func realPart(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(FloatValue{value: real(x)})
+	return successResult
+}
+
+func imaginaryPart(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(FloatValue{value: imag(x)})
+	return successResult
+}
+
+func absolute(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if x.GetType() == IntegerType {
+		value := integerOf(x)
+		if value < 0 {
+			value = -value
+		}
+		core.Push(IntegerValue{value: value})
+		return successResult
+	}
+	if !isComplex(x) {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(FloatValue{value: cmplx.Abs(complexOf(x))})
+	return successResult
+}
+
+func argument(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(FloatValue{value: cmplx.Phase(x)})
+	return successResult
+}
+
+func conjugate(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(ComplexValue{value: cmplx.Conj(x)})
+	return successResult
+}
+
+func realToComplex(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	if !isNumeric(x) || !isNumeric(y) {
+		return InstructionResult{true, "Expected real numbers"}
+	}
+	core.Push(ComplexValue{value: complex(y.GetFloat(), x.GetFloat())})
+	return successResult
+}
+
+func complexToReal(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	core.Push(FloatValue{value: real(x)})
+	core.Push(FloatValue{value: imag(x)})
+	return successResult
+}
+
+// Convert rectangular (x,y) to polar (r,θ), with θ in radians
+func rectangularToPolar(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	r, theta := cmplx.Polar(x)
+	core.Push(ComplexValue{value: complex(r, theta)})
+	return successResult
+}
+
+// Convert polar (r,θ), with θ in radians, to rectangular (x,y)
+func polarToRectangular(core *Core) InstructionResult {
+	x, ok := consumeComplex(core)
+	if !ok {
+		return InstructionResult{true, "Expected a number"}
+	}
+	r, theta := real(x), imag(x)
+	core.Push(ComplexValue{value: complex(r*math.Cos(theta), r*math.Sin(theta))})
+	return successResult
+}
@@ -1,54 +1,104 @@
 package core
 
-func lessThan(core *Core) InstructionResult {
-	x, y := consumeTwo(core)
-	if x.GetFloat() <= y.GetFloat() {
-		core.Regs.State.ResultFlag = true
-	} else {
-		core.Regs.State.ResultFlag = false
+// Push the result of a comparison, or only set the result flag in flag mode
+func compared(core *Core, result bool) InstructionResult {
+	if core.Regs.FlagMode {
+		core.Regs.State.ResultFlag = result
+		return successResult
 	}
+	core.Push(BoolValue{value: result})
 	return successResult
 }
 
+// Choose whether comparisons push a boolean or set the result flag, for programs written
+// before boolean values that test the flag apart from the comparison
+func setFlagMode(flagMode bool) InstructionImpl {
+	return func(core *Core) InstructionResult {
+		core.Regs.FlagMode = flagMode
+		return successResult
+	}
+}
+
+func lessThan(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	return compared(core, x.GetFloat() <= y.GetFloat())
+}
+
 func greaterThan(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	if x.GetFloat() >= y.GetFloat() {
-		core.Regs.State.ResultFlag = true
-	} else {
-		core.Regs.State.ResultFlag = false
-	}
-	return successResult
+	return compared(core, x.GetFloat() >= y.GetFloat())
 }
 
 func equals(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	if x.GetType() == StringType {
-		if x.GetString() == y.GetString() {
-			core.Regs.State.ResultFlag = true
-			return successResult
-		}
-		core.Regs.State.ResultFlag = false
-		return successResult
+	return compared(core, isEqual(x, y))
+}
+
+func notEquals(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	return compared(core, !isEqual(x, y))
+}
+
+// Compare complex numbers by both parts, strings by their text, and other values by
+// their real value
+func isEqual(x CoreValue, y CoreValue) bool {
+	if x.GetType() == ComplexType || y.GetType() == ComplexType {
+		return isComplex(x) && isComplex(y) && complexOf(x) == complexOf(y)
 	}
-	if x.GetFloat() == y.GetFloat() {
-		core.Regs.State.ResultFlag = true
-		return successResult
+	if x.GetType() == StringType {
+		return x.GetString() == y.GetString()
 	}
+	return x.GetFloat() == y.GetFloat()
+}
+
+func unset(core *Core) InstructionResult {
 	core.Regs.State.ResultFlag = false
 	return successResult
 }
 
-func notEquals(core *Core) InstructionResult {
+func ifThen(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	if x.GetFloat() != y.GetFloat() {
-		core.Regs.State.ResultFlag = true
-	} else {
-		core.Regs.State.ResultFlag = false
+	condition, ok := truthOf(y)
+	if !ok {
+		return InstructionResult{true, "Expected a boolean condition"}
+	}
+	if condition {
+		core.EvalValue(x)
 	}
 	return successResult
 }
 
-func unset(core *Core) InstructionResult {
-	core.Regs.State.ResultFlag = false
+func ifThenElse(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	condition, ok := truthOf(consumeOne(core))
+	if !ok {
+		return InstructionResult{true, "Expected a boolean condition"}
+	}
+	if condition {
+		core.EvalValue(y)
+	} else {
+		core.EvalValue(x)
+	}
 	return successResult
 }
+
+// Take the condition for ceval and ceval2, which is the result flag in flag mode and
+// otherwise the boolean beneath the sequences, returning false if there is none
+func consumeCondition(core *Core, count int) (bool, bool) {
+	if core.Regs.FlagMode {
+		return core.Regs.State.ResultFlag, true
+	}
+	value := core.currentStack().PeekAt(count)
+	if value == nil || (*value).GetType() != BoolType {
+		return false, false
+	}
+	sequences := make([]CoreValue, count)
+	for i := count - 1; i >= 0; i-- {
+		sequences[i] = consumeOne(core)
+	}
+	condition := consumeOne(core).(BoolValue).value
+	for _, sequence := range sequences {
+		core.Push(sequence)
+	}
+	return condition, true
+}
@@ -0,0 +1,42 @@
+package core
+
+func try(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	err, caught := core.evalCatching(y)
+	if caught {
+		core.Push(err)
+		core.EvalValue(x)
+	}
+	return successResult
+}
+
+func catch(core *Core) InstructionResult {
+	x := consumeOne(core)
+	err, caught := core.evalCatching(x)
+	if caught {
+		core.Push(err)
+		return successResult
+	}
+	core.Push(DefaultValue{})
+	return successResult
+}
+
+func throw(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if err, ok := x.(ErrorValue); ok {
+		core.raiseValue(err)
+		return successResult
+	}
+	core.raise(ErrThrown, core.Format(x))
+	return successResult
+}
+
+func errorNumber(core *Core) InstructionResult {
+	core.Push(FloatValue{value: float64(core.LastError.code)})
+	return successResult
+}
+
+func errorMessage(core *Core) InstructionResult {
+	core.Push(StringValue{value: core.LastError.message})
+	return successResult
+}
@@ -1,59 +1,109 @@
 package core
 
 func ceval(core *Core) InstructionResult {
-	if core.Regs.State.ResultFlag {
-		eval(core)
-	} else {
-		_ = consumeOne(core)
+	condition, ok := consumeCondition(core, 1)
+	x := consumeOne(core)
+	if !ok {
+		return InstructionResult{true, "Expected a boolean condition"}
+	}
+	if condition {
+		core.EvalValue(x)
 	}
 	return successResult
 }
 
 func ceval2(core *Core) InstructionResult {
+	condition, ok := consumeCondition(core, 2)
 	x, y := consumeTwo(core)
-	if core.Regs.State.ResultFlag {
-		core.EvalSequence(y.GetSequence())
+	if !ok {
+		return InstructionResult{true, "Expected a boolean condition"}
+	}
+	if condition {
+		core.EvalValue(y)
 	} else {
-		core.EvalSequence(x.GetSequence())
+		core.EvalValue(x)
 	}
 	return successResult
 }
 
 func eval(core *Core) InstructionResult {
 	x := consumeOne(core)
-	core.EvalSequence(x.GetSequence())
+	core.EvalValue(x)
 	return successResult
 }
 
 func apply(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	results := make([]CoreValue, len(y.GetSequence()))
-	core.NewStack()
-	for i, value := range y.GetSequence() {
+	m, isMap := y.(MapValue)
+	values := y.GetSequence()
+	if isMap {
+		values = m.values
+	}
+	results, status := applyEach(core, x, values)
+	if results == nil {
+		return status
+	}
+	if isMap {
+		result := m.copy()
+		result.values = results
+		core.Push(result)
+		return successResult
+	}
+	core.Push(SequenceValue{value: results})
+	return successResult
+}
+
+// Evaluate x against each value on a stack of its own, replacing the value with the one x
+// leaves on top. Values after a stop are left as they are. The results are nil if x leaves
+// no result, or raises an error, which is left to propagate
+func applyEach(core *Core, x CoreValue, values []CoreValue) ([]CoreValue, InstructionResult) {
+	results := append([]CoreValue{}, values...)
+	if !core.NewStack() {
+		return nil, successResult
+	}
+	for i, value := range values {
 		core.Push(value)
 		core.Push(x)
 		eval(core)
-		result := core.Pop()
-		if result != nil {
-			results[i] = *result
-		} else {
-			results[i] = DefaultValue{}
-			Logger.Printf("Error: no result during apply")
+		if core.failed() {
+			core.DropStack()
+			return nil, successResult
 		}
+		if core.ShouldBreak() {
+			break
+		}
+		if core.currentStack().length == 0 {
+			core.DropStack()
+			return nil, InstructionResult{true, "Expected a result from x"}
+		}
+		results[i] = consumeOne(core)
 	}
 	core.DropStack()
-	core.Push(SequenceValue{value: results})
-	return successResult
+	return results, successResult
 }
 
 func each(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	core.NewStack()
+	if !core.NewStack() {
+		return successResult
+	}
+	if m, ok := y.(MapValue); ok {
+		for i, key := range m.keys {
+			core.Push(key)
+			core.Push(m.values[i])
+			core.evalBody(x)
+			if core.failed() || core.ShouldBreak() {
+				break
+			}
+		}
+		core.DropStack()
+		return successResult
+	}
 	for i, value := range y.GetSequence() {
 		core.Push(FloatValue{value: float64(i)})
 		core.Push(value)
-		core.EvalSequence(x.GetSequence())
-		if core.ShouldBreak() {
+		core.evalBody(x)
+		if core.failed() || core.ShouldBreak() {
 			break
 		}
 	}
@@ -63,12 +113,14 @@ func each(core *Core) InstructionResult {
 
 func stream(core *Core) InstructionResult {
 	x := consumeOne(core)
-	core.NewStack()
+	if !core.NewStack() {
+		return successResult
+	}
 	for i := 0; i < 2760; i++ {
 		core.Push(FloatValue{value: float64(core.Ram[i])})
 		core.Push(x)
 		eval(core)
-		if core.ShouldBreak() {
+		if core.failed() || core.ShouldBreak() {
 			break
 		}
 	}
@@ -78,16 +130,31 @@ func stream(core *Core) InstructionResult {
 
 func reduce(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	core.NewStack()
 	values := y.GetSequence()
-	offset := 1
+	if len(values) == 0 {
+		return InstructionResult{true, "Expected at least one value to reduce"}
+	}
+	if !core.NewStack() {
+		return successResult
+	}
 	lastResult := values[0]
-	for ; offset < len(values); offset++ {
+	for _, value := range values[1:] {
 		core.Push(lastResult)
-		core.Push(values[offset])
+		core.Push(value)
 		core.Push(x)
 		eval(core)
-		lastResult = *core.Pop()
+		if core.failed() {
+			core.DropStack()
+			return successResult
+		}
+		if core.ShouldBreak() {
+			break
+		}
+		if core.currentStack().length == 0 {
+			core.DropStack()
+			return InstructionResult{true, "Expected a result from x"}
+		}
+		lastResult = consumeOne(core)
 	}
 	core.DropStack()
 	core.Push(lastResult)
@@ -99,7 +166,9 @@ func generate(core *Core) InstructionResult {
 	if len(x) != 2 {
 		return InstructionResult{true, "Invalid generator sequence"}
 	}
-	core.NewStack()
+	if !core.NewStack() {
+		return successResult
+	}
 	core.Push(x[0])
 	core.EvalSequence(x[1].GetSequence())
 	result := consumeOne(core)
@@ -111,10 +180,37 @@ func generate(core *Core) InstructionResult {
 }
 
 func repeat(core *Core) InstructionResult {
-	x := consumeOne(core).GetSequence()
-	completed := core.EvalSequence(x)
-	for i := 0; completed && i < 10000; i++ {
-		completed = core.EvalSequence(x)
+	x := consumeOne(core)
+	completed := core.evalBody(x)
+	for i := 0; completed && (core.Quotas.MaxRepeat <= 0 || i < core.Quotas.MaxRepeat); i++ {
+		if !core.checkIteration() {
+			break
+		}
+		completed = core.evalBody(x)
+	}
+	return successResult
+}
+
+func importProgram(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if x.GetType() != StringType {
+		return InstructionResult{true, "Expected a program name"}
+	}
+	return core.importModule(x.GetString())
+}
+
+// Limit the definitions of the module being imported that are visible outside of it
+func export(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if x.GetType() != SequenceType {
+		return InstructionResult{true, "Expected a sequence of names"}
+	}
+	if len(core.env.importing) == 0 {
+		return successResult
+	}
+	m := core.env.importing[len(core.env.importing)-1]
+	for _, name := range x.GetSequence() {
+		m.exports = append(m.exports, name.GetString())
 	}
 	return successResult
 }
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// Convert x to the simplest fraction that agrees with it to 12 significant digits,
+// or to the precision of a decimal
+func toFraction(core *Core) InstructionResult {
+	x := consumeOne(core)
+	switch v := x.(type) {
+	case IntegerValue, RationalValue:
+		core.Push(x)
+		return successResult
+	case DecimalValue:
+		exact, _ := v.value.Rat(nil)
+		tolerance := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.digits)), nil))
+		core.Push(newRational(fraction(exact, tolerance)))
+		return successResult
+	case FloatValue:
+		exact, _ := new(big.Rat).SetString(strconv.FormatFloat(v.value, 'g', -1, 64))
+		if exact == nil {
+			return InstructionResult{true, "Expected a finite number"}
+		}
+		core.Push(newRational(fraction(exact, big.NewRat(1, 1e12))))
+		return successResult
+	}
+	return InstructionResult{true, "Expected a number"}
+}
+
+// Set the digits carried by the results of decimal arithmetic
+func precision(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if !isNumeric(x) || x.GetInt() < 1 || x.GetInt() > 10000 {
+		return InstructionResult{true, "Expected a precision between 1 and 10000 digits"}
+	}
+	core.Regs.Precision = x.GetInt()
+	return successResult
+}
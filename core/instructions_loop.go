@@ -13,15 +13,9 @@ func decrement(core *Core) InstructionResult {
 
 func loopNotZero(core *Core) InstructionResult {
 	x := consumeOne(core)
-	var sequence []CoreValue
-	if x.GetType() != SequenceType {
-		sequence = x.GetSequence()
-	} else {
-		sequence = x.(SequenceValue).value
-	}
 	run := true
-	for core.Regs.LoopCounter != 0 && run {
-		run = core.EvalSequence(sequence)
+	for core.Regs.LoopCounter != 0 && run && core.checkIteration() {
+		run = core.evalBody(x)
 		decrement(core)
 		if core.ShouldBreak() {
 			break
@@ -0,0 +1,55 @@
+package core
+
+func mapGet(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	m, ok := y.(MapValue)
+	if !ok {
+		return InstructionResult{true, "Expected a map"}
+	}
+	value, ok := m.Get(x)
+	if !ok {
+		return InstructionResult{true, "Key not found"}
+	}
+	core.Push(value)
+	return successResult
+}
+
+func mapPut(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	m, ok := consumeOne(core).(MapValue)
+	if !ok {
+		return InstructionResult{true, "Expected a map"}
+	}
+	core.Push(m.Put(y, x))
+	return successResult
+}
+
+func mapDelete(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	m, ok := y.(MapValue)
+	if !ok {
+		return InstructionResult{true, "Expected a map"}
+	}
+	core.Push(m.Delete(x))
+	return successResult
+}
+
+func mapKeys(core *Core) InstructionResult {
+	m, ok := consumeOne(core).(MapValue)
+	if !ok {
+		return InstructionResult{true, "Expected a map"}
+	}
+	core.Push(SequenceValue{value: m.Keys()})
+	return successResult
+}
+
+func mapHas(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	m, ok := y.(MapValue)
+	if !ok {
+		return InstructionResult{true, "Expected a map"}
+	}
+	_, ok = m.Get(x)
+	core.Push(BoolValue{value: ok})
+	return successResult
+}
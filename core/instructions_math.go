@@ -2,11 +2,56 @@ package core
 
 import (
 	"math"
+	"math/big"
+	"math/cmplx"
 	"math/rand"
 )
 
+// Apply an operation to two numbers, producing an integer only when both are integers
+func arithmetic(x CoreValue, y CoreValue, intOp func(y, x int64) int64, floatOp func(y, x float64) float64) (CoreValue, bool) {
+	if !isNumeric(x) || !isNumeric(y) {
+		return DefaultValue{}, false
+	}
+	if x.GetType() == IntegerType && y.GetType() == IntegerType {
+		return IntegerValue{value: intOp(integerOf(y), integerOf(x))}, true
+	}
+	return FloatValue{value: floatOp(y.GetFloat(), x.GetFloat())}, true
+}
+
+// Apply an operation to two numbers when either of them is complex
+func complexArithmetic(x CoreValue, y CoreValue, op func(y, x complex128) complex128) (CoreValue, bool) {
+	if x.GetType() != ComplexType && y.GetType() != ComplexType {
+		return DefaultValue{}, false
+	}
+	if !isComplex(x) || !isComplex(y) {
+		return DefaultValue{}, false
+	}
+	return ComplexValue{value: op(complexOf(y), complexOf(x))}, true
+}
+
 func add(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
+	if x.GetType() == AlgebraicType || y.GetType() == AlgebraicType {
+		return algebraicArithmetic(core, x, y, "+")
+	}
+	if x.GetType() == QuantityType || y.GetType() == QuantityType {
+		return quantityArithmetic(core, x, y, '+')
+	}
+	if x.GetType() == ArrayType || y.GetType() == ArrayType {
+		return arrayElementwise(core, x, y, func(y, x float64) float64 { return y + x })
+	}
+	if result, ok := complexArithmetic(x, y, func(y, x complex128) complex128 { return y + x }); ok {
+		core.Push(result)
+		return successResult
+	}
+	if result, ok := exactArithmetic(core, x, y, (*big.Rat).Add, (*big.Float).Add); ok {
+		core.Push(result)
+		return successResult
+	}
+	if result, ok := arithmetic(x, y, func(y, x int64) int64 { return y + x }, func(y, x float64) float64 { return y + x }); ok {
+		core.Push(result)
+		return successResult
+	}
 	if x.GetType() == FloatType {
 		core.Push(FloatValue{value: y.GetFloat() + x.GetFloat()})
 		return successResult
@@ -25,6 +70,27 @@ func add(core *Core) InstructionResult {
 
 func subtract(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
+	if x.GetType() == AlgebraicType || y.GetType() == AlgebraicType {
+		return algebraicArithmetic(core, x, y, "-")
+	}
+	if x.GetType() == QuantityType || y.GetType() == QuantityType {
+		return quantityArithmetic(core, x, y, '-')
+	}
+	if x.GetType() == ArrayType || y.GetType() == ArrayType {
+		return arrayElementwise(core, x, y, func(y, x float64) float64 { return y - x })
+	}
+	if result, ok := complexArithmetic(x, y, func(y, x complex128) complex128 { return y - x }); ok {
+		core.Push(result)
+		return successResult
+	}
+	if result, ok := exactArithmetic(core, x, y, (*big.Rat).Sub, (*big.Float).Sub); ok {
+		core.Push(result)
+		return successResult
+	}
+	if result, ok := arithmetic(x, y, func(y, x int64) int64 { return y - x }, func(y, x float64) float64 { return y - x }); ok {
+		core.Push(result)
+		return successResult
+	}
 	if x.GetType() == FloatType {
 		core.Push(FloatValue{value: y.GetFloat() - x.GetFloat()})
 		return successResult
@@ -38,6 +104,30 @@ func subtract(core *Core) InstructionResult {
 
 func multiply(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
+	if x.GetType() == AlgebraicType || y.GetType() == AlgebraicType {
+		return algebraicArithmetic(core, x, y, "*")
+	}
+	if x.GetType() == QuantityType || y.GetType() == QuantityType {
+		return quantityArithmetic(core, x, y, '*')
+	}
+	if x.GetType() == ArrayType && y.GetType() == ArrayType {
+		return matrixProduct(core, x.(ArrayValue), y.(ArrayValue))
+	}
+	if x.GetType() == ArrayType || y.GetType() == ArrayType {
+		return arrayScale(core, x, y, func(y, x float64) float64 { return y * x })
+	}
+	if result, ok := complexArithmetic(x, y, func(y, x complex128) complex128 { return y * x }); ok {
+		core.Push(result)
+		return successResult
+	}
+	if result, ok := exactArithmetic(core, x, y, (*big.Rat).Mul, (*big.Float).Mul); ok {
+		core.Push(result)
+		return successResult
+	}
+	if result, ok := arithmetic(x, y, func(y, x int64) int64 { return y * x }, func(y, x float64) float64 { return y * x }); ok {
+		core.Push(result)
+		return successResult
+	}
 	if y.GetType() == FloatType {
 		core.Push(FloatValue{value: y.GetFloat() * x.GetFloat()})
 		return successResult
@@ -65,7 +155,38 @@ func vplus(core *Core) InstructionResult {
 
 func divide(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	if x.GetType() == FloatType {
+	if x.GetType() == AlgebraicType || y.GetType() == AlgebraicType {
+		return algebraicArithmetic(core, x, y, "/")
+	}
+	if x.GetType() == QuantityType || y.GetType() == QuantityType {
+		return quantityArithmetic(core, x, y, '/')
+	}
+	if x.GetType() == ArrayType && y.GetType() == ArrayType {
+		result, status := solveSystem(x.(ArrayValue), y.(ArrayValue))
+		if status.error {
+			return status
+		}
+		core.Push(result)
+		return successResult
+	}
+	if y.GetType() == ArrayType {
+		return arrayScale(core, x, y, func(y, x float64) float64 { return y / x })
+	}
+	if x.GetType() == ComplexType && complexOf(x) == 0 {
+		return InstructionResult{true, "Divide by zero"}
+	}
+	if result, ok := complexArithmetic(x, y, func(y, x complex128) complex128 { return y / x }); ok {
+		core.Push(result)
+		return successResult
+	}
+	if (isExact(x) || isExact(y) || x.GetType() == IntegerType) && signOf(x) == 0 {
+		return InstructionResult{true, "Divide by zero"}
+	}
+	if result, ok := exactArithmetic(core, x, y, (*big.Rat).Quo, (*big.Float).Quo); ok {
+		core.Push(result)
+		return successResult
+	}
+	if isNumeric(x) {
 		core.Push(FloatValue{value: y.GetFloat() / x.GetFloat()})
 		return successResult
 	}
@@ -78,8 +199,22 @@ func divide(core *Core) InstructionResult {
 
 func modulus(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	if x.GetType() == FloatType {
-		core.Push(FloatValue{value: float64(y.GetInt() % x.GetInt())})
+	if x.GetType() == IntegerType && y.GetType() == IntegerType {
+		if integerOf(x) == 0 {
+			return InstructionResult{true, "Divide by zero"}
+		}
+		core.Push(IntegerValue{value: integerOf(y) % integerOf(x)})
+		return successResult
+	}
+	if isNumeric(x) && signOf(x) == 0 {
+		return InstructionResult{true, "Divide by zero"}
+	}
+	if result, ok := exactArithmetic(core, x, y, ratModulus, decimalModulus); ok {
+		core.Push(result)
+		return successResult
+	}
+	if isNumeric(x) {
+		core.Push(FloatValue{value: math.Mod(y.GetFloat(), x.GetFloat())})
 		return successResult
 	}
 	if x.GetType() == StringType {
@@ -89,8 +224,41 @@ func modulus(core *Core) InstructionResult {
 	return InstructionResult{true, "Unexpected operands"}
 }
 
+func integerDivide(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	if !isNumeric(x) || !isNumeric(y) {
+		return InstructionResult{true, "Unexpected operands"}
+	}
+	if integerOf(x) == 0 {
+		return InstructionResult{true, "Divide by zero"}
+	}
+	core.Push(IntegerValue{value: integerOf(y) / integerOf(x)})
+	return successResult
+}
+
 func inverse(core *Core) InstructionResult {
 	x := consumeOne(core)
+	if a, ok := x.(ArrayValue); ok {
+		return matrixInverse(core, a)
+	}
+	if c, ok := x.(ComplexValue); ok {
+		if c.value == 0 {
+			return InstructionResult{true, "Divide by zero"}
+		}
+		core.Push(ComplexValue{value: 1 / c.value})
+		return successResult
+	}
+	if r, ok := x.(RationalValue); ok {
+		core.Push(newRational(new(big.Rat).Inv(r.value)))
+		return successResult
+	}
+	if d, ok := x.(DecimalValue); ok {
+		if d.value.Sign() == 0 {
+			return InstructionResult{true, "Divide by zero"}
+		}
+		core.Push(DecimalValue{value: new(big.Float).SetPrec(d.value.Prec()).Quo(big.NewFloat(1), d.value), digits: d.digits})
+		return successResult
+	}
 	val := x.GetFloat()
 	if val == 0 {
 		return InstructionResult{true, "Divide by zero"}
@@ -101,6 +269,10 @@ func inverse(core *Core) InstructionResult {
 
 func sin(core *Core) InstructionResult {
 	x := consumeOne(core)
+	if c, ok := x.(ComplexValue); ok {
+		core.Push(ComplexValue{value: cmplx.Sin(c.value)})
+		return successResult
+	}
 	result := math.Sin(x.GetFloat())
 	core.Push(FloatValue{value: result})
 	return successResult
@@ -108,6 +280,10 @@ func sin(core *Core) InstructionResult {
 
 func cos(core *Core) InstructionResult {
 	x := consumeOne(core)
+	if c, ok := x.(ComplexValue); ok {
+		core.Push(ComplexValue{value: cmplx.Cos(c.value)})
+		return successResult
+	}
 	result := math.Cos(x.GetFloat())
 	core.Push(FloatValue{value: result})
 	return successResult
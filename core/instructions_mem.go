@@ -2,6 +2,7 @@ package core
 
 import (
 	"math"
+	"path/filepath"
 )
 
 func store(core *Core) InstructionResult {
@@ -19,23 +20,58 @@ func move(core *Core) InstructionResult {
 
 func exchange(core *Core) InstructionResult {
 	x, y := consumeTwo(core)
-	xVal := Variables[x.GetString()]
-	if xVal == nil {
+	xVal, ok := core.variable(x.GetString())
+	if !ok {
 		return InstructionResult{true, "Variable not set"}
 	}
-	Variables[x.GetString()] = y
+	core.setVariable(x.GetString(), y)
 	core.Push(xVal)
 	return successResult
 }
 
+// Bind the values beneath y to the names in y, the first name taking the deepest value,
+// and evaluate x with them as local variables
+func bindLocals(core *Core) InstructionResult {
+	// Check the names and the values for them before consuming anything, so that a failure
+	// leaves the stack as it was
+	y := *core.currentStack().PeekAt(1)
+	if y.GetType() != SequenceType {
+		return InstructionResult{true, "Expected a sequence of names"}
+	}
+	names := y.GetSequence()
+	for _, name := range names {
+		if name.GetType() != StringType {
+			return InstructionResult{true, "Invalid local name: " + name.GetString()}
+		}
+	}
+	if core.currentStack().length < len(names)+2 {
+		return InstructionResult{true, "Too few arguments"}
+	}
+	x, _ := consumeTwo(core)
+	locals := make(map[string]CoreValue, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		locals[names[i].GetString()] = consumeOne(core)
+	}
+	if sequence, ok := x.(SequenceValue); ok {
+		core.evalSequence(sequence.name, sequence, locals)
+		return successResult
+	}
+	core.evalFrame("", x.GetSequence(), locals)
+	return successResult
+}
+
 func get(core *Core) InstructionResult {
 	x := consumeOne(core)
-	if x.GetType() == FloatType && int(x.GetFloat()) < len(core.Ram) {
-		core.Push(FloatValue{value: float64(core.Ram[int(x.GetFloat())])})
+	if isNumeric(x) && x.GetInt() >= 0 && x.GetInt() < len(core.Ram) {
+		core.Push(IntegerValue{value: int64(core.Ram[x.GetInt()])})
+		return successResult
+	}
+	if locals := core.localScope(x.GetString()); locals != nil {
+		core.Push(locals[x.GetString()])
 		return successResult
 	}
-	val := Variables[x.GetString()]
-	if val == nil {
+	val, ok := core.variable(x.GetString())
+	if !ok {
 		return InstructionResult{true, "Variable not set"}
 	}
 	core.Push(val)
@@ -49,13 +85,16 @@ func deref(core *Core) InstructionResult {
 
 func purge(core *Core) InstructionResult {
 	x := consumeOne(core)
-	Variables[x.GetString()] = nil
+	if !core.visible(x.GetString()) {
+		return InstructionResult{true, "Variable not visible"}
+	}
+	core.env.Purge(x.GetString())
 	return successResult
 }
 
 func mmap(core *Core) InstructionResult {
 	x := consumeOne(core)
-	bytes := RawData[x.GetString()]
+	bytes := core.env.rom.RawData[x.GetString()]
 	if bytes == nil {
 		return InstructionResult{true, "File not found"}
 	}
@@ -68,11 +107,56 @@ func mmap(core *Core) InstructionResult {
 }
 
 func files(core *Core) InstructionResult {
-	for k := range Programs {
-		core.Control <- CommandMessage{Command: Output, Arg: "Program: " + k}
+	for k := range core.env.rom.Programs {
+		core.Emit(Output, "Program: "+k)
+	}
+	for k := range core.env.rom.RawData {
+		core.Emit(Output, "Data: "+k)
+	}
+	return successResult
+}
+
+func romErrors(core *Core) InstructionResult {
+	for _, d := range core.env.rom.Diagnostics {
+		core.Emit(Output, "ROM error: "+d.Error())
+	}
+	return successResult
+}
+
+// Resolve the file named by x within the snapshot directory, so that programs cannot read
+// or write files elsewhere
+func snapshotPath(core *Core, x CoreValue) (string, InstructionResult) {
+	if x.GetType() != StringType {
+		return "", InstructionResult{true, "Expected a file name"}
+	}
+	if !filepath.IsLocal(x.GetString()) {
+		return "", InstructionResult{true, "Expected a file within the snapshot directory"}
+	}
+	return filepath.Join(core.SnapshotDir, x.GetString()), successResult
+}
+
+func save(core *Core) InstructionResult {
+	path, result := snapshotPath(core, consumeOne(core))
+	if result.error {
+		return result
+	}
+	err := core.Save(path)
+	if err != nil {
+		Logger.Printf("Error: Failed to save state: path=%s, err=%s\n", path, err.Error())
+		return InstructionResult{true, "Failed to save state"}
+	}
+	return successResult
+}
+
+func restore(core *Core) InstructionResult {
+	path, result := snapshotPath(core, consumeOne(core))
+	if result.error {
+		return result
 	}
-	for k := range RawData {
-		core.Control <- CommandMessage{Command: Output, Arg: "Data: " + k}
+	err := core.Restore(path)
+	if err != nil {
+		Logger.Printf("Error: Failed to restore state: path=%s, err=%s\n", path, err.Error())
+		return InstructionResult{true, "Failed to restore state"}
 	}
 	return successResult
 }
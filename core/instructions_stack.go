@@ -11,14 +11,15 @@ func end(core *Core) InstructionResult {
 }
 
 func defineSequence(core *Core) InstructionResult {
-	core.NewStack()
-	core.Regs.Mode = Storing
+	if core.NewStack() {
+		core.Regs.Mode = Storing
+	}
 	return successResult
 }
 
 func reduceSequence(core *Core) InstructionResult {
 	steps := core.currentStack().ToArray()
-	value := SequenceValue{value: steps}
+	value := compiledSequence(steps)
 	core.DropStack()
 	core.Regs.Mode = Running
 	core.Push(value)
@@ -26,7 +27,7 @@ func reduceSequence(core *Core) InstructionResult {
 }
 
 func this(core *Core) InstructionResult {
-	core.Push(SequenceValue{value: currentSequence})
+	core.Push(SequenceValue{value: core.env.currentSequence})
 	return successResult
 }
 
@@ -68,6 +69,9 @@ func clear(core *Core) InstructionResult {
 }
 
 func collect(core *Core) InstructionResult {
+	if (*core.currentStack().Peek()).GetType() == AlgebraicType {
+		return collectAlgebraic(core)
+	}
 	value := SequenceValue{value: core.GetStackArray()}
 	core.ClearStack()
 	core.Push(value)
@@ -75,6 +79,9 @@ func collect(core *Core) InstructionResult {
 }
 
 func expand(core *Core) InstructionResult {
+	if (*core.currentStack().Peek()).GetType() == AlgebraicType {
+		return expandAlgebraic(core)
+	}
 	x := consumeOne(core)
 	values := x.GetSequence()
 	for i := range values {
@@ -0,0 +1,116 @@
+package core
+
+import "math"
+
+// Apply an operation to two values when either of them carries units
+func quantityArithmetic(core *Core, x CoreValue, y CoreValue, op byte) InstructionResult {
+	qx, xOk := x.(QuantityValue)
+	qy, yOk := y.(QuantityValue)
+	if !xOk {
+		if !isNumeric(x) {
+			return InstructionResult{true, "Unexpected operands"}
+		}
+		qx = QuantityValue{value: x.GetFloat()}
+	}
+	if !yOk {
+		if !isNumeric(y) {
+			return InstructionResult{true, "Unexpected operands"}
+		}
+		qy = QuantityValue{value: y.GetFloat()}
+	}
+
+	switch op {
+	case '+', '-':
+		converted, ok := qx.convert(qy.units)
+		if !ok {
+			return InstructionResult{true, "Inconsistent units"}
+		}
+		if op == '+' {
+			core.Push(newQuantity(qy.value+converted.value, qy.units))
+		} else {
+			core.Push(newQuantity(qy.value-converted.value, qy.units))
+		}
+	case '*':
+		core.Push(newQuantity(qy.value*qx.value, qy.units.multiply(qx.units, 1)))
+	case '/':
+		if qx.value == 0 {
+			return InstructionResult{true, "Divide by zero"}
+		}
+		core.Push(newQuantity(qy.value/qx.value, qy.units.multiply(qx.units, -1)))
+	}
+	return successResult
+}
+
+// Raise a quantity to the integer power x, raising its units with its magnitude
+func quantityPower(core *Core, q QuantityValue, x CoreValue) InstructionResult {
+	if !isNumeric(x) || x.GetFloat() != math.Trunc(x.GetFloat()) {
+		return InstructionResult{true, "Expected an integer power of a quantity"}
+	}
+	n := x.GetInt()
+	units := Units{}
+	for _, term := range q.units {
+		units = append(units, unitTerm{name: term.name, power: term.power * n})
+	}
+	core.Push(newQuantity(math.Pow(q.value, float64(n)), units.simplify()))
+	return successResult
+}
+
+// Take the square root of a quantity, halving the powers of its units as written, or in
+// SI base units when those are not all even, such as for acres
+func quantityRoot(core *Core, q QuantityValue) InstructionResult {
+	value, units := q.value, q.units
+	if !units.even() {
+		factor, dims := units.base()
+		value, units = value*factor, dims.units()
+	}
+	if !units.even() {
+		return InstructionResult{true, "Expected units with even powers"}
+	}
+	halved := Units{}
+	for _, term := range units {
+		halved = append(halved, unitTerm{name: term.name, power: term.power / 2})
+	}
+	core.Push(newQuantity(math.Sqrt(value), halved))
+	return successResult
+}
+
+func convert(core *Core) InstructionResult {
+	x, y := consumeTwo(core)
+	q, ok := y.(QuantityValue)
+	if !ok {
+		return InstructionResult{true, "Expected a quantity"}
+	}
+	units, ok := unitsOf(x)
+	if !ok {
+		return InstructionResult{true, "Invalid units"}
+	}
+	converted, ok := q.convert(units)
+	if !ok {
+		return InstructionResult{true, "Inconsistent units"}
+	}
+	core.Push(converted)
+	return successResult
+}
+
+func unitBase(core *Core) InstructionResult {
+	q, ok := consumeOne(core).(QuantityValue)
+	if !ok {
+		return InstructionResult{true, "Expected a quantity"}
+	}
+	factor, dims := q.units.base()
+	core.Push(newQuantity(q.value*factor, dims.units()))
+	return successResult
+}
+
+func unitValue(core *Core) InstructionResult {
+	x := consumeOne(core)
+	if q, ok := x.(QuantityValue); ok {
+		core.Push(FloatValue{value: q.value})
+		return successResult
+	}
+	if !isNumeric(x) {
+		return InstructionResult{true, "Expected a quantity"}
+	}
+	core.Push(x)
+	return successResult
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// A token of input, with the line and column where it starts, counting from 1
+type Token struct {
+	Text   string
+	Line   int
+	Column int
+	// Set for strings written in double quotes, whose text is the unescaped string
+	Quoted bool
+}
+
+type lexer struct {
+	input  []rune
+	pos    int
+	line   int
+	column int
+}
+
+var closingBrackets = map[rune]rune{'[': ']', '{': '}', '(': ')'}
+
+// Split input into whitespace separated tokens. Strings may be written in double quotes
+// to include spaces, with \", \\, \n and \t escapes. A # followed by hex digits is an
+// integer such as #ff, while any other # starts a comment that runs to the end of the
+// line, as in legacy ROM files. Brackets group a literal such as [1, 2] into a single token
+func Tokenize(input string) ([]Token, error) {
+	l := lexer{input: []rune(input), line: 1, column: 1}
+	tokens := []Token{}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.input) {
+			return tokens, nil
+		}
+		token := Token{Line: l.line, Column: l.column}
+		var err error
+		switch {
+		case l.input[l.pos] == '"':
+			token.Text, err = l.quoted()
+			token.Quoted = true
+		case l.input[l.pos] == '#' && l.isComment():
+			l.skipLine()
+			continue
+		default:
+			token.Text, err = l.word()
+			if _, ok := parseInteger(token.Text); err == nil && strings.HasPrefix(token.Text, "#") && !ok {
+				return nil, Diagnostic{Line: token.Line, Column: token.Column, Token: token.Text, Message: "invalid integer"}
+			}
+		}
+		if err != nil {
+			return nil, Diagnostic{Line: token.Line, Column: token.Column, Message: err.Error()}
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func (l *lexer) next() rune {
+	r := l.input[l.pos]
+	l.pos++
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.next()
+	}
+}
+
+func (l *lexer) skipLine() {
+	for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+		l.next()
+	}
+}
+
+// Check if the # at the current position starts a comment, which it does unless it is
+// followed by nothing but hex digits up to the next whitespace
+func (l *lexer) isComment() bool {
+	end := l.pos + 1
+	for end < len(l.input) && !unicode.IsSpace(l.input[end]) {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", l.input[end]) {
+			return true
+		}
+		end++
+	}
+	return end == l.pos+1
+}
+
+func (l *lexer) quoted() (string, error) {
+	l.next()
+	var sb strings.Builder
+	for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+		r := l.next()
+		switch r {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if l.pos >= len(l.input) {
+				return "", fmt.Errorf("unterminated string")
+			}
+			escaped := l.next()
+			switch escaped {
+			case '"', '\\':
+				sb.WriteRune(escaped)
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				return "", fmt.Errorf("unknown escape: \\%c", escaped)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// Read up to the next whitespace. A literal that starts with a bracket or an array bar
+// runs to its closing bracket or bar, including any whitespace and double quoted strings,
+// whose escaped quotes do not end them
+func (l *lexer) word() (string, error) {
+	start := l.pos
+	closing := []rune{}
+	if r := l.input[l.pos]; r == '|' {
+		closing = append(closing, '|')
+		l.next()
+	} else if closingBrackets[r] != 0 {
+		closing = append(closing, closingBrackets[r])
+		l.next()
+	}
+	grouped := len(closing) > 0
+	quoted := false
+	for l.pos < len(l.input) && (len(closing) > 0 || !unicode.IsSpace(l.input[l.pos])) {
+		r := l.next()
+		switch {
+		case !grouped:
+		case quoted && r == '\\' && l.pos < len(l.input):
+			l.next()
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case len(closing) > 0 && r == closing[len(closing)-1]:
+			closing = closing[:len(closing)-1]
+		case closingBrackets[r] != 0:
+			closing = append(closing, closingBrackets[r])
+		}
+	}
+	if len(closing) > 0 {
+		return "", fmt.Errorf("missing %c", closing[len(closing)-1])
+	}
+	return string(l.input[start:l.pos]), nil
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The built-in instructions, for naming instructions inside sequence literals
+var builtinInstructions func() map[string]Instruction
+
+func init() {
+	builtinInstructions = sync.OnceValue(newInstructionMap)
+}
+
+type literalParser struct {
+	input string
+	pos   int
+}
+
+// Parse a sequence literal such as [1,'a,$x,+,[2,3]] or a map literal such as {'pc:23},
+// which may nest sequences, maps, double quoted strings, references and instructions
+func parseLiteral(input string) (CoreValue, error) {
+	p := literalParser{input: input}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %s after literal", p.input[p.pos:])
+	}
+	return value, nil
+}
+
+func (p *literalParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("column %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *literalParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// Consume a delimiter, skipping whitespace before it
+func (p *literalParser) accept(delimiter byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == delimiter {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *literalParser) value() (CoreValue, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("missing value")
+	}
+	switch p.input[p.pos] {
+	case '[':
+		return p.sequence()
+	case '{':
+		return p.mapping()
+	case '"':
+		return p.quoted()
+	}
+	return p.scalar()
+}
+
+func (p *literalParser) sequence() (CoreValue, error) {
+	p.pos++
+	values := []CoreValue{}
+	if p.accept(']') {
+		return SequenceValue{value: values}, nil
+	}
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.accept(']') {
+			return SequenceValue{value: values}, nil
+		}
+		if !p.accept(',') {
+			return nil, p.errorf("expected , or ] in sequence")
+		}
+	}
+}
+
+func (p *literalParser) mapping() (CoreValue, error) {
+	p.pos++
+	result := MapValue{index: map[string]int{}}
+	if p.accept('}') {
+		return result, nil
+	}
+	for {
+		key, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(':') {
+			return nil, p.errorf("expected : after map key")
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		result = result.Put(key, value)
+		if p.accept('}') {
+			return result, nil
+		}
+		if !p.accept(',') {
+			return nil, p.errorf("expected , or } in map")
+		}
+	}
+}
+
+func (p *literalParser) quoted() (CoreValue, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.input) && p.input[p.pos] != '"' {
+		if p.input[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		p.pos = start
+		return nil, p.errorf("unterminated string")
+	}
+	p.pos++
+	text := p.input[start:p.pos]
+	tokens, err := Tokenize(text)
+	if err != nil || len(tokens) != 1 {
+		p.pos = start
+		return nil, p.errorf("invalid string %s", text)
+	}
+	return StringValue{value: tokens[0].Text}, nil
+}
+
+// Read a value up to the next delimiter, keeping the commas of complex numbers and arrays
+func (p *literalParser) scalar() (CoreValue, error) {
+	start := p.pos
+	depth := 0
+	bars := false
+	for ; p.pos < len(p.input); p.pos++ {
+		r := p.input[p.pos]
+		if r == '(' {
+			depth++
+		} else if r == ')' {
+			depth--
+		} else if r == '|' {
+			bars = !bars
+		} else if depth == 0 && !bars && strings.IndexByte(",]}:", r) >= 0 {
+			break
+		}
+	}
+	text := strings.TrimSpace(p.input[start:p.pos])
+	if text == "" {
+		p.pos = start
+		return nil, p.errorf("missing value")
+	}
+	value := RawToImmediateCoreValue(text)
+	if value.GetType() != DefaultType {
+		return value, nil
+	}
+	if instruction, ok := builtinInstructions()[text]; ok {
+		return InstructionValue{value: instruction, name: text}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("invalid value %s", text)
+}
+
+// Describe a value in the literal syntax that parses back to it
+func literalString(value CoreValue) string {
+	return describeLiteral(value, func(value CoreValue) string {
+		if v, ok := value.(IntegerValue); ok {
+			if v.value < 0 {
+				return "-0d" + strconv.FormatInt(-v.value, 10)
+			}
+			return "0d" + strconv.FormatInt(v.value, 10)
+		}
+		return value.GetString()
+	})
+}
+
+// Describe a value in the literal syntax, describing the values that are not
+// instructions, references, strings, sequences or maps with scalar
+func describeLiteral(value CoreValue, scalar func(CoreValue) string) string {
+	switch v := value.(type) {
+	case InstructionValue:
+		return v.name
+	case ReferenceValue:
+		return "$" + v.value
+	case StringValue:
+		if v.value == "" || strings.ContainsAny(v.value, " \t\n\"\\,[]{}():|") || strings.HasSuffix(v.value, "'") {
+			return quote(v.value)
+		}
+		return "'" + v.value
+	case SequenceValue:
+		entries := make([]string, len(v.value))
+		for i, entry := range v.value {
+			entries[i] = describeLiteral(entry, scalar)
+		}
+		return "[" + strings.Join(entries, ",") + "]"
+	case MapValue:
+		entries := make([]string, len(v.keys))
+		for i := range v.keys {
+			entries[i] = describeLiteral(v.keys[i], scalar) + ":" + describeLiteral(v.values[i], scalar)
+		}
+		return "{" + strings.Join(entries, ",") + "}"
+	}
+	return scalar(value)
+}
+
+// Write a string in double quotes, escaping quotes, backslashes, newlines and tabs
+func quote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
@@ -0,0 +1,63 @@
+package core
+
+import "testing"
+
+func TestLiteralRoundTrip(t *testing.T) {
+	inputs := []string{
+		`[1, [2, [3, 4]], "a,b", $x, +]`,
+		`["a \"q\" b", 'name, "", "x y"]`,
+		`{'pc:[1,2], 'name:"x y", 'inner:{'k:-0.25}}`,
+		`[2_m, 9.8_m/s^2, [(1,2), (-0.5,3)]]`,
+		`[#ff, 0b1010, -#1, 1/3, 1.5d, 'x^2+1', |1,2;3,4|]`,
+		`[[], {}, [[[]]]]`,
+	}
+	for _, input := range inputs {
+		value, err := parseLiteral(input)
+		if err != nil {
+			t.Fatalf("parseLiteral(%s): %v", input, err)
+		}
+		text := literalString(value)
+		reparsed, err := parseLiteral(text)
+		if err != nil {
+			t.Fatalf("parseLiteral(%s), printed from %s: %v", text, input, err)
+		}
+		if again := literalString(reparsed); again != text {
+			t.Errorf("%s printed as %s, which printed as %s once parsed again", input, text, again)
+		}
+		if !sameValue(value, reparsed) {
+			t.Errorf("%s parsed again as a different value from %s", input, text)
+		}
+	}
+}
+
+// Compare values by type, element by element for sequences and maps
+func sameValue(a CoreValue, b CoreValue) bool {
+	if a.GetType() != b.GetType() {
+		return false
+	}
+	switch a := a.(type) {
+	case SequenceValue:
+		b := b.(SequenceValue)
+		if len(a.value) != len(b.value) {
+			return false
+		}
+		for i := range a.value {
+			if !sameValue(a.value[i], b.value[i]) {
+				return false
+			}
+		}
+		return true
+	case MapValue:
+		b := b.(MapValue)
+		if len(a.keys) != len(b.keys) {
+			return false
+		}
+		for i := range a.keys {
+			if !sameValue(a.keys[i], b.keys[i]) || !sameValue(a.values[i], b.values[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.GetString() == b.GetString()
+}
@@ -0,0 +1,17 @@
+package core
+
+// Find the local variables binding name, searching the frames from the innermost out.
+// Locals are only visible within the sequence that binds them and the unnamed sequences
+// it evaluates, so the search stops at the frame of a program or variable, which keeps
+// the locals of a caller apart from those of the programs it calls, including itself
+func (c *Core) localScope(name string) map[string]CoreValue {
+	for i := len(c.frames) - 1; i >= 0; i-- {
+		if _, ok := c.frames[i].Locals[name]; ok {
+			return c.frames[i].Locals
+		}
+		if c.frames[i].Name != "" {
+			return nil
+		}
+	}
+	return nil
+}
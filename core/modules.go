@@ -0,0 +1,132 @@
+package core
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// A ROM program imported as a module. The definitions it stores while it is imported
+// are published as variables named after the module, such as player.fDrawPc
+type module struct {
+	program     string
+	namespace   string
+	definitions map[string]CoreValue
+	// The definitions visible outside of the module, or nil to export every definition
+	exports []string
+	loaded  bool
+}
+
+// Evaluate a ROM program as a module, unless it has already been imported
+func (c *Core) importModule(program string) InstructionResult {
+	if m, ok := c.env.modules[program]; ok && m.loaded {
+		return successResult
+	}
+	for i, m := range c.env.importing {
+		if m.program == program {
+			cycle := []string{}
+			for _, m := range c.env.importing[i:] {
+				cycle = append(cycle, m.program)
+			}
+			return InstructionResult{true, "Import cycle: " + strings.Join(append(cycle, program), " -> ")}
+		}
+	}
+	sequence, ok := c.env.Program(program)
+	if !ok {
+		return InstructionResult{true, "Module not found: " + program}
+	}
+	m := &module{program: program, namespace: path.Base(program), definitions: map[string]CoreValue{}}
+	if other, ok := c.namespaceOwner(m.namespace); ok {
+		return InstructionResult{true, "Module " + program + " has the same namespace as " + other}
+	}
+	c.env.importing = append(c.env.importing, m)
+	if c.NewStack() {
+		c.evalFrame(program, sequence.value, nil)
+		c.DropStack()
+	}
+	c.env.importing = c.env.importing[:len(c.env.importing)-1]
+	if c.failed() {
+		return successResult
+	}
+	for _, name := range m.exports {
+		if _, ok := m.definitions[name]; !ok {
+			return InstructionResult{true, "Module " + program + " does not define " + name}
+		}
+	}
+	for name, value := range m.definitions {
+		c.setVariable(m.namespace+"."+name, m.qualify(value))
+	}
+	m.loaded = true
+	c.env.modules[program] = m
+	return successResult
+}
+
+// Find the program of the module imported, or being imported, with a namespace
+func (c *Core) namespaceOwner(namespace string) (string, bool) {
+	for _, m := range c.env.modules {
+		if m.namespace == namespace {
+			return m.program, true
+		}
+	}
+	for _, m := range c.env.importing {
+		if m.namespace == namespace {
+			return m.program, true
+		}
+	}
+	return "", false
+}
+
+// Rewrite the references of the module's sequences to its own definitions, so that
+// they still refer to them once they are published with the namespace
+func (m *module) qualify(value CoreValue) CoreValue {
+	switch v := value.(type) {
+	case ReferenceValue:
+		if _, ok := m.definitions[v.value]; ok {
+			return ReferenceValue{value: m.namespace + "." + v.value}
+		}
+	case SequenceValue:
+		values := make([]CoreValue, len(v.value))
+		for i, nested := range v.value {
+			values[i] = m.qualify(nested)
+		}
+		sequence := compiledSequence(values)
+		sequence.name = v.name
+		return sequence
+	}
+	return value
+}
+
+// Check if a variable is visible. A definition a module does not export is only
+// visible within the definitions of that module, which alone may read, replace or purge it
+func (c *Core) visible(name string) bool {
+	namespace, definition, ok := strings.Cut(name, ".")
+	if !ok {
+		return true
+	}
+	for _, m := range c.env.modules {
+		if m.namespace != namespace || m.exports == nil || slices.Contains(m.exports, definition) {
+			continue
+		}
+		return c.namespace() == namespace
+	}
+	return true
+}
+
+// Get a variable, if it is visible from the code being evaluated
+func (c *Core) variable(name string) (CoreValue, bool) {
+	if !c.visible(name) {
+		return nil, false
+	}
+	return c.env.Variable(name)
+}
+
+// The namespace of the innermost program or variable being evaluated, if any
+func (c *Core) namespace() string {
+	for i := len(c.frames) - 1; i >= 0; i-- {
+		if c.frames[i].Name != "" {
+			namespace, _, _ := strings.Cut(c.frames[i].Name, ".")
+			return namespace
+		}
+	}
+	return ""
+}
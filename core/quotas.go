@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// Limits applied to each top level evaluation, where zero means unlimited
+	Quotas struct {
+		// Values evaluated, including instructions
+		MaxInstructions int
+		// Entries on any single stack
+		MaxStackEntries int
+		// Stacks on the stack of stacks
+		MaxStackDepth int
+		// Bytes held by any single value, counting the values nested in sequences
+		MaxValueBytes int
+		MaxVariables  int
+		// Iterations of a single repeat instruction
+		MaxRepeat int
+		Timeout   time.Duration
+		// Frames of nested evaluation, such as recursive calls. Zero means DefaultMaxFrames,
+		// since unbounded recursion would overflow the Go stack
+		MaxFrames int
+	}
+	quotaUsage struct {
+		instructions int
+		deadline     time.Time
+	}
+)
+
+var DefaultQuotas = Quotas{MaxRepeat: 10000}
+
+const DefaultMaxFrames = 10000
+
+func (c *Core) resetUsage() {
+	c.usage = quotaUsage{}
+	if c.Quotas.Timeout > 0 {
+		c.usage.deadline = time.Now().Add(c.Quotas.Timeout)
+	}
+}
+
+// Count an evaluated value, raising an error if the instruction budget or deadline is exceeded
+func (c *Core) checkBudget() bool {
+	c.usage.instructions++
+	if c.Quotas.MaxInstructions > 0 && c.usage.instructions > c.Quotas.MaxInstructions {
+		c.raise(ErrInstructionQuota, fmt.Sprintf("Instruction quota of %d exceeded", c.Quotas.MaxInstructions))
+		return false
+	}
+	if !c.usage.deadline.IsZero() && time.Now().After(c.usage.deadline) {
+		c.raise(ErrDeadline, fmt.Sprintf("Deadline of %s exceeded", c.Quotas.Timeout))
+		return false
+	}
+	return true
+}
+
+// Check between the iterations of a loop, whose body may be empty and never check itself,
+// that it has not been interrupted and is within its budget
+func (c *Core) checkIteration() bool {
+	return !c.interrupted() && c.checkBudget()
+}
+
+func (c *Core) checkFrames() bool {
+	limit := c.Quotas.MaxFrames
+	if limit <= 0 {
+		limit = DefaultMaxFrames
+	}
+	if len(c.frames) >= limit {
+		c.raise(ErrFrameQuota, fmt.Sprintf("Frame quota of %d exceeded", limit))
+		return false
+	}
+	return true
+}
+
+func (c *Core) checkStackEntries() bool {
+	if c.Quotas.MaxStackEntries > 0 && c.currentStack().Len() >= c.Quotas.MaxStackEntries {
+		c.raise(ErrStackQuota, fmt.Sprintf("Stack quota of %d entries exceeded", c.Quotas.MaxStackEntries))
+		return false
+	}
+	return true
+}
+
+func (c *Core) checkStackDepth() bool {
+	if c.Quotas.MaxStackDepth > 0 && c.stackStack.Len() >= c.Quotas.MaxStackDepth {
+		c.raise(ErrStackDepthQuota, fmt.Sprintf("Stack depth quota of %d exceeded", c.Quotas.MaxStackDepth))
+		return false
+	}
+	return true
+}
+
+func (c *Core) checkValueBytes(value CoreValue) bool {
+	if c.Quotas.MaxValueBytes > 0 && valueBytes(value, c.Quotas.MaxValueBytes) > c.Quotas.MaxValueBytes {
+		c.raise(ErrValueQuota, fmt.Sprintf("Value quota of %d bytes exceeded", c.Quotas.MaxValueBytes))
+		return false
+	}
+	return true
+}
+
+func (c *Core) checkVariables(name string) bool {
+	if c.Quotas.MaxVariables <= 0 {
+		return true
+	}
+	if _, ok := c.env.variables[name]; !ok && len(c.env.variables) >= c.Quotas.MaxVariables {
+		c.raise(ErrVariableQuota, fmt.Sprintf("Variable quota of %d exceeded", c.Quotas.MaxVariables))
+		return false
+	}
+	return true
+}
+
+func (c *Core) setVariable(name string, value CoreValue) {
+	if c.checkVariables(name) && c.checkValueBytes(value) {
+		c.env.SetVariable(name, value)
+	}
+}
+
+// Estimate the bytes held by a value, stopping once limit is exceeded
+func valueBytes(value CoreValue, limit int) int {
+	switch v := value.(type) {
+	case StringValue:
+		return len(v.value)
+	case ReferenceValue:
+		return len(v.value)
+	case SequenceValue:
+		total := 8
+		for _, nested := range v.value {
+			total += valueBytes(nested, limit-total)
+			if total > limit {
+				break
+			}
+		}
+		return total
+	case ComplexValue:
+		return 16
+	case ArrayValue:
+		return 8 * len(v.data)
+	case RationalValue:
+		return 8 + (v.value.Num().BitLen()+v.value.Denom().BitLen())/8
+	case DecimalValue:
+		return 8 + int(v.value.Prec())/8
+	case AlgebraicValue:
+		return len(v.value.String())
+	case MapValue:
+		total := 8
+		for i := range v.keys {
+			total += valueBytes(v.keys[i], limit-total) + valueBytes(v.values[i], limit-total)
+			if total > limit {
+				break
+			}
+		}
+		return total
+	}
+	return 8
+}
@@ -2,43 +2,130 @@ package core
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 	"strings"
 )
 
-var RawData map[string][]byte = make(map[string][]byte)
-var Programs map[string]SequenceValue = make(map[string]SequenceValue)
-var Symbols []rune = []rune{}
+// Rom is the read-only layer shared by every core created from it
+type Rom struct {
+	Programs     map[string]SequenceValue
+	RawData      map[string][]byte
+	Symbols      []rune
+	constants    map[string]CoreValue
+	instructions map[string]Instruction
+	root         string
+	// Problems found while loading the ROM, for the files that could not be loaded
+	Diagnostics []Diagnostic
+}
+
+// Create a ROM with the built-in constants and instructions, but no files
+func NewRom() *Rom {
+	return &Rom{
+		Programs:     make(map[string]SequenceValue),
+		RawData:      make(map[string][]byte),
+		Symbols:      []rune{},
+		constants:    newConstants(),
+		instructions: newInstructionMap(),
+	}
+}
+
+func LoadRom(root string) (*Rom, error) {
+	rom := NewRom()
+	rom.root = root
+	err := filepath.Walk(root, rom.loadFile)
+	if err != nil {
+		return nil, err
+	}
+	err = rom.loadSymbols()
+	if err != nil {
+		return nil, err
+	}
+	return rom, nil
+}
+
+// Load a program from an arbitrary path, outside of the ROM, returning every problem
+// found in it as a Diagnostic
+func (r *Rom) LoadProgram(path string) (SequenceValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SequenceValue{}, err
+	}
+	result, diagnostics := r.parseProgram(path, r.programDir(path), string(data))
+	if len(diagnostics) != 0 {
+		errs := make([]error, len(diagnostics))
+		for i, d := range diagnostics {
+			errs[i] = d
+		}
+		return SequenceValue{}, errors.Join(errs...)
+	}
+	result.name = strings.TrimSuffix(filepath.Base(path), ".28")
+	return result, nil
+}
 
-func LoadRom() error {
-	err := filepath.Walk("rom/", loadFile)
-	loadSymbols()
-	return err
+// Convert the source of a program in a file to a sequence, collecting its problems
+func (r *Rom) parseProgram(file string, dir string, source string) (SequenceValue, []Diagnostic) {
+	tokens, err := Tokenize(source)
+	if err != nil {
+		return SequenceValue{}, inFile(file, nil, err)
+	}
+	diagnostics := []Diagnostic{}
+	_, result := r.convertToSequence(0, tokens, nil, &diagnostics)
+	result.value = r.withImports(result.value, importDirectives(dir, source, &diagnostics))
+	return result, inFile(file, diagnostics, nil)
 }
 
-func loadFile(path string, info os.FileInfo, err error) error {
-	if info.IsDir() || err != nil {
+// The directory of a program within the ROM, which its imports are relative to,
+// or the ROM root for a program outside of it
+func (r *Rom) programDir(path string) string {
+	if r.root == "" {
+		return "."
+	}
+	rel, err := filepath.Rel(r.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "."
+	}
+	return pathpkg.Dir(filepath.ToSlash(rel))
+}
+
+// Load a file into the ROM, recording a diagnostic for a file that cannot be loaded
+// rather than stopping
+func (r *Rom) loadFile(path string, info os.FileInfo, err error) error {
+	if err != nil {
+		r.Diagnostics = append(r.Diagnostics, inFile(filepath.ToSlash(path), nil, err)...)
+		return nil
+	}
+	if info.IsDir() {
 		return nil
 	}
 	fileName := filepath.Base(path)
 	if strings.HasPrefix(fileName, ".") {
 		return nil
 	}
-	name := strings.Replace(path, "rom/", "", -1)
+	name, err := filepath.Rel(r.root, path)
+	if err != nil {
+		return nil
+	}
+	name = filepath.ToSlash(name)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
+		r.Diagnostics = append(r.Diagnostics, inFile(name, nil, err)...)
 		return nil
 	}
 
 	if strings.HasSuffix(fileName, ".28") {
+		result, diagnostics := r.parseProgram(name, pathpkg.Dir(name), string(data))
+		if len(diagnostics) != 0 {
+			r.Diagnostics = append(r.Diagnostics, diagnostics...)
+			return nil
+		}
 		name = strings.Replace(name, ".28", "", -1)
-		inputs := strings.Split(string(data[:]), "\n")
-		_, result := convertToSequence(0, inputs)
-		Programs[name] = result
+		result.name = name
+		r.Programs[name] = result
 		return nil
 	}
 
@@ -51,7 +138,7 @@ func loadFile(path string, info os.FileInfo, err error) error {
 				n++
 			}
 		}
-		RawData[name] = []byte(data[:n])
+		r.RawData[name] = []byte(data[:n])
 	}
 
 	if strings.HasSuffix(fileName, ".raw") {
@@ -62,46 +149,93 @@ func loadFile(path string, info os.FileInfo, err error) error {
 				n++
 			}
 		}
-		RawData[name] = []byte(data[:n])
+		r.RawData[name] = []byte(data[:n])
 	}
 
 	return nil
 }
 
-func loadSymbols() {
-	raw, err := os.ReadFile("rom/symbols.set")
+func (r *Rom) loadSymbols() error {
+	raw, err := os.ReadFile(filepath.Join(r.root, "symbols.set"))
 	if err != nil {
-		log.Fatal("Could not read symbols")
+		return fmt.Errorf("could not read symbols: %w", err)
 	}
-	Symbols = bytes.Runes(raw)
-	fmt.Printf("Symbols=%s", string(Symbols[:]))
+	r.Symbols = bytes.Runes(raw)
+	Logger.Printf("Symbols=%s\n", string(r.Symbols[:]))
+	return nil
 }
 
-func convertToSequence(offset int, inputs []string) (int, SequenceValue) {
-	values := []CoreValue{}
-	for ; offset < len(inputs); offset++ {
-		input := strings.TrimLeft(inputs[offset], " \t")
-		if input == "" {
+// Find the programs named by #import directives, such as #import player, which are
+// relative to dir unless they start with a slash. A directive may be followed by a
+// comment, and any other directive is recorded in diagnostics
+func importDirectives(dir string, source string, diagnostics *[]Diagnostic) []string {
+	imports := []string{}
+	for i, line := range strings.Split(source, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "#import" {
 			continue
 		}
-		if input[0] == '#' {
+		if len(fields) < 2 || len(fields) > 2 && fields[2] != "#" {
+			column := strings.Index(line, "#import") + 1
+			*diagnostics = append(*diagnostics, Diagnostic{Line: i + 1, Column: column, Token: strings.TrimSpace(line), Message: "malformed directive"})
 			continue
 		}
-		if input == "<" {
-			newOffset, value := convertToSequence(offset+1, inputs)
+		if strings.HasPrefix(fields[1], "/") {
+			imports = append(imports, strings.TrimPrefix(fields[1], "/"))
+		} else {
+			imports = append(imports, pathpkg.Join(dir, fields[1]))
+		}
+	}
+	return imports
+}
+
+// Import each program before the rest of a sequence, which is evaluated from its end
+func (r *Rom) withImports(sequence []CoreValue, imports []string) []CoreValue {
+	for i := len(imports) - 1; i >= 0; i-- {
+		sequence = append(sequence, r.RawToInstruction("import"), StringValue{value: imports[i]})
+	}
+	return sequence
+}
+
+// Convert tokens to a sequence up to the > closing the < at opening, or to the end at
+// the top level, recording invalid tokens and unbalanced brackets in diagnostics
+func (r *Rom) convertToSequence(offset int, tokens []Token, opening *Token, diagnostics *[]Diagnostic) (int, SequenceValue) {
+	values := []CoreValue{}
+	for ; offset < len(tokens); offset++ {
+		token := tokens[offset]
+		if token.Text == "<" && !token.Quoted {
+			newOffset, value := r.convertToSequence(offset+1, tokens, &tokens[offset], diagnostics)
 			offset = newOffset
 			values = append([]CoreValue{value}, values...)
 
-		} else if input == ">" {
-			return offset, SequenceValue{value: values}
+		} else if token.Text == ">" && !token.Quoted {
+			if opening != nil {
+				return offset, compiledSequence(values)
+			}
+			*diagnostics = append(*diagnostics, Diagnostic{Line: token.Line, Column: token.Column, Message: "unbalanced >, with no < before it"})
 
 		} else {
-			value := RawToCoreValue(input)
+			value := r.TokenToCoreValue(token)
 			if value.GetType() == DefaultType {
-				panic(fmt.Sprintf("Invalid input: input=%s", input))
+				message := "invalid value"
+				if strings.HasPrefix(token.Text, "[") || strings.HasPrefix(token.Text, "{") {
+					if _, err := parseLiteral(token.Text); err != nil {
+						message = "invalid literal (" + err.Error() + ")"
+					}
+				}
+				if text, ok := algebraicText(token.Text); ok {
+					if _, err := NewAlgebraicValue(text); err != nil {
+						message = "invalid expression (" + err.Error() + ")"
+					}
+				}
+				*diagnostics = append(*diagnostics, Diagnostic{Line: token.Line, Column: token.Column, Token: token.Text, Message: message})
+				continue
 			}
 			values = append([]CoreValue{value}, values...)
 		}
 	}
-	return offset, SequenceValue{value: values}
+	if opening != nil {
+		*diagnostics = append(*diagnostics, Diagnostic{Line: opening.Line, Column: opening.Column, Message: "unbalanced <, with no > to close it"})
+	}
+	return offset, compiledSequence(values)
 }
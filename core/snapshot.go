@@ -0,0 +1,358 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+// The version of the snapshot format, which restore requires to match
+const snapshotVersion = 1
+
+type (
+	snapshot struct {
+		Version   int                      `json:"version"`
+		Stacks    [][]snapshotValue        `json:"stacks"`
+		Variables map[string]snapshotValue `json:"variables"`
+		Ram       []byte                   `json:"ram"`
+		Registers snapshotRegisters        `json:"registers"`
+		Error     snapshotValue            `json:"error"`
+		LastError snapshotValue            `json:"lastError"`
+		Ticks     int64                    `json:"ticks,omitempty"`
+	}
+	snapshotRegisters struct {
+		ResultFlag  bool          `json:"resultFlag"`
+		BreakFlag   bool          `json:"breakFlag"`
+		PromptFlag  bool          `json:"promptFlag"`
+		Mode        ExecutionMode `json:"mode"`
+		LoopCounter int16         `json:"loopCounter"`
+		Precision   int           `json:"precision"`
+		Base        int           `json:"base"`
+		WordSize    int           `json:"wordSize"`
+		Display     DisplayMode   `json:"display"`
+		Digits      int           `json:"digits"`
+		FlagMode    bool          `json:"flagMode"`
+	}
+	snapshotValue struct {
+		Type   string          `json:"type"`
+		Value  string          `json:"value,omitempty"`
+		Values []snapshotValue `json:"values,omitempty"`
+	}
+)
+
+// Write the complete state of the core to a file
+func (c *Core) Save(path string) error {
+	s := snapshot{
+		Version:   snapshotVersion,
+		Variables: map[string]snapshotValue{},
+		Ram:       c.Ram,
+		Registers: snapshotRegisters{
+			ResultFlag:  c.Regs.State.ResultFlag,
+			BreakFlag:   c.Regs.State.BreakFlag,
+			PromptFlag:  c.Regs.State.PromptFlag,
+			Mode:        c.Regs.Mode,
+			LoopCounter: c.Regs.LoopCounter,
+			Precision:   c.Regs.Precision,
+			Base:        c.Regs.Base,
+			WordSize:    c.Regs.WordSize,
+			Display:     c.Regs.Display,
+			Digits:      c.Regs.Digits,
+			FlagMode:    c.Regs.FlagMode,
+		},
+		Error:     encodeValue(c.Error),
+		LastError: encodeValue(c.LastError),
+		Ticks:     c.Ticks,
+	}
+	stacks := c.stackStack.ToArray()
+	for i := len(stacks) - 1; i >= 0; i-- {
+		s.Stacks = append(s.Stacks, encodeValues(stacks[i].ToArray()))
+	}
+	for k, v := range c.env.variables {
+		s.Variables[k] = encodeValue(v)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Replace the state of the core with a snapshot written by Save
+func (c *Core) Restore(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s := snapshot{}
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", s.Version)
+	}
+	if len(s.Stacks) == 0 {
+		return fmt.Errorf("snapshot has no stacks")
+	}
+
+	stacks := make([]Stack[CoreValue], len(s.Stacks))
+	for i, encoded := range s.Stacks {
+		values, err := c.decodeValues(encoded)
+		if err != nil {
+			return err
+		}
+		for j := len(values) - 1; j >= 0; j-- {
+			stacks[i].Push(values[j])
+		}
+	}
+	variables := map[string]CoreValue{}
+	for k, encoded := range s.Variables {
+		value, err := c.decodeValue(encoded)
+		if err != nil {
+			return err
+		}
+		variables[k] = value
+	}
+	coreErr, err := c.decodeValue(s.Error)
+	if err != nil {
+		return err
+	}
+	value, err := c.decodeValue(s.LastError)
+	if err != nil {
+		return err
+	}
+	lastErr, ok := value.(ErrorValue)
+	if !ok {
+		return fmt.Errorf("invalid last error in snapshot: %s", s.LastError.Type)
+	}
+
+	c.stackStack = Stack[Stack[CoreValue]]{}
+	for _, stack := range stacks {
+		c.stackStack.Push(stack)
+	}
+	if c.stackStack.top.prev != nil {
+		c.prevStack = &c.stackStack.top.prev.value
+	} else {
+		c.prevStack = nil
+	}
+	c.env.variables = variables
+	// Modules are imported again, since their definitions may not be in the snapshot
+	c.env.modules = make(map[string]*module)
+	c.Ram = make([]byte, len(c.Ram))
+	copy(c.Ram, s.Ram)
+	c.Regs.State.ResultFlag = s.Registers.ResultFlag
+	c.Regs.State.BreakFlag = s.Registers.BreakFlag
+	c.Regs.State.PromptFlag = s.Registers.PromptFlag
+	c.Regs.Mode = s.Registers.Mode
+	c.Regs.LoopCounter = s.Registers.LoopCounter
+	c.Regs.Precision = s.Registers.Precision
+	c.Regs.Base = s.Registers.Base
+	c.Regs.WordSize = s.Registers.WordSize
+	c.Regs.Display = s.Registers.Display
+	c.Regs.Digits = s.Registers.Digits
+	c.Regs.FlagMode = s.Registers.FlagMode
+	c.Error = coreErr
+	c.LastError = lastErr
+	c.Ticks = s.Ticks
+	return nil
+}
+
+func encodeValues(values []CoreValue) []snapshotValue {
+	result := make([]snapshotValue, len(values))
+	for i, value := range values {
+		result[i] = encodeValue(value)
+	}
+	return result
+}
+
+func encodeValue(value CoreValue) snapshotValue {
+	switch v := value.(type) {
+	case FloatValue:
+		return snapshotValue{Type: "float", Value: strconv.FormatFloat(v.value, 'g', -1, 64)}
+	case IntegerValue:
+		return snapshotValue{Type: "integer", Value: strconv.FormatInt(v.value, 10)}
+	case ComplexValue:
+		return snapshotValue{Type: "complex", Value: strconv.FormatComplex(v.value, 'g', -1, 128)}
+	case ArrayValue:
+		values := make([]CoreValue, len(v.data))
+		for i, value := range v.data {
+			values[i] = FloatValue{value: value}
+		}
+		shape := fmt.Sprintf("%dx%d", v.rows, v.cols)
+		if v.vector {
+			shape = strconv.Itoa(v.cols)
+		}
+		return snapshotValue{Type: "array", Value: shape, Values: encodeValues(values)}
+	case QuantityValue:
+		return snapshotValue{Type: "quantity", Value: strconv.FormatFloat(v.value, 'g', -1, 64) + "_" + v.units.String()}
+	case RationalValue:
+		return snapshotValue{Type: "rational", Value: v.value.RatString()}
+	case DecimalValue:
+		// In hexadecimal, with its digits and bits of precision, so it is restored exactly
+		return snapshotValue{Type: "decimal", Value: v.value.Text('p', 0), Values: encodeValues([]CoreValue{
+			IntegerValue{value: int64(v.digits)}, IntegerValue{value: int64(v.value.Prec())}})}
+	case AlgebraicValue:
+		return snapshotValue{Type: "algebraic", Value: v.value.String()}
+	case BoolValue:
+		return snapshotValue{Type: "bool", Value: strconv.FormatBool(v.value)}
+	case StringValue:
+		return snapshotValue{Type: "string", Value: v.value}
+	case SequenceValue:
+		return snapshotValue{Type: "sequence", Values: encodeValues(v.value)}
+	case MapValue:
+		entries := []CoreValue{}
+		for i := range v.keys {
+			entries = append(entries, v.keys[i], v.values[i])
+		}
+		return snapshotValue{Type: "map", Values: encodeValues(entries)}
+	case InstructionValue:
+		return snapshotValue{Type: "instruction", Value: v.name}
+	case ReferenceValue:
+		return snapshotValue{Type: "reference", Value: v.value}
+	case ErrorValue:
+		trace := make([]CoreValue, len(v.trace))
+		for i, frame := range v.trace {
+			trace[i] = StringValue{value: frame}
+		}
+		return snapshotValue{Type: "error", Value: v.message, Values: encodeValues([]CoreValue{
+			FloatValue{value: float64(v.code)}, StringValue{value: v.instruction}, SequenceValue{value: trace}})}
+	}
+	return snapshotValue{Type: "default"}
+}
+
+func (c *Core) decodeValues(values []snapshotValue) ([]CoreValue, error) {
+	result := make([]CoreValue, len(values))
+	for i, encoded := range values {
+		value, err := c.decodeValue(encoded)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+func (c *Core) decodeValue(encoded snapshotValue) (CoreValue, error) {
+	switch encoded.Type {
+	case "float":
+		value, err := strconv.ParseFloat(encoded.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float in snapshot: %s", encoded.Value)
+		}
+		return FloatValue{value: value}, nil
+	case "integer":
+		value, err := strconv.ParseInt(encoded.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer in snapshot: %s", encoded.Value)
+		}
+		return IntegerValue{value: value}, nil
+	case "complex":
+		value, err := strconv.ParseComplex(encoded.Value, 128)
+		if err != nil {
+			return nil, fmt.Errorf("invalid complex in snapshot: %s", encoded.Value)
+		}
+		return ComplexValue{value: value}, nil
+	case "array":
+		values, err := c.decodeValues(encoded.Values)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]float64, len(values))
+		for i, value := range values {
+			data[i] = value.GetFloat()
+		}
+		rows, cols := 0, 0
+		if _, err := fmt.Sscanf(encoded.Value, "%dx%d", &rows, &cols); err == nil && rows*cols == len(data) {
+			return ArrayValue{rows: rows, cols: cols, data: data}, nil
+		}
+		if cols, err := strconv.Atoi(encoded.Value); err == nil && cols == len(data) {
+			return NewVectorValue(data), nil
+		}
+		return nil, fmt.Errorf("invalid array in snapshot: %s", encoded.Value)
+	case "quantity":
+		value := RawToImmediateCoreValue(encoded.Value)
+		if value.GetType() != QuantityType {
+			return nil, fmt.Errorf("invalid quantity in snapshot: %s", encoded.Value)
+		}
+		return value, nil
+	case "rational":
+		value, ok := parseRational(encoded.Value)
+		if !ok {
+			return nil, fmt.Errorf("invalid rational in snapshot: %s", encoded.Value)
+		}
+		return newRational(value), nil
+	case "decimal":
+		values, err := c.decodeValues(encoded.Values)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 || values[0].GetType() != IntegerType || values[1].GetType() != IntegerType {
+			return nil, fmt.Errorf("invalid decimal in snapshot: %s", encoded.Value)
+		}
+		value, _, err := big.ParseFloat(encoded.Value, 0, uint(values[1].GetInt()), big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decimal in snapshot: %s", encoded.Value)
+		}
+		return DecimalValue{value: value, digits: values[0].GetInt()}, nil
+	case "algebraic":
+		value, err := NewAlgebraicValue(encoded.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression in snapshot: %s", encoded.Value)
+		}
+		return value, nil
+	case "bool":
+		value, err := strconv.ParseBool(encoded.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool in snapshot: %s", encoded.Value)
+		}
+		return BoolValue{value: value}, nil
+	case "string":
+		return StringValue{value: encoded.Value}, nil
+	case "sequence":
+		values, err := c.decodeValues(encoded.Values)
+		if err != nil {
+			return nil, err
+		}
+		return compiledSequence(values), nil
+	case "map":
+		entries, err := c.decodeValues(encoded.Values)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries)%2 != 0 {
+			return nil, fmt.Errorf("invalid map in snapshot: %d entries", len(entries))
+		}
+		result := MapValue{index: map[string]int{}}
+		for i := 0; i < len(entries); i += 2 {
+			result = result.Put(entries[i], entries[i+1])
+		}
+		return result, nil
+	case "instruction":
+		value := c.env.rom.RawToInstruction(encoded.Value)
+		if value.GetType() != InstructionType {
+			return nil, fmt.Errorf("unknown instruction in snapshot: %s", encoded.Value)
+		}
+		return value, nil
+	case "reference":
+		return ReferenceValue{value: encoded.Value}, nil
+	case "error":
+		values, err := c.decodeValues(encoded.Values)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 3 || values[0].GetType() != FloatType || values[2].GetType() != SequenceType {
+			return nil, fmt.Errorf("invalid error in snapshot: %s", encoded.Value)
+		}
+		trace := []string{}
+		for _, frame := range values[2].GetSequence() {
+			trace = append(trace, frame.GetString())
+		}
+		return ErrorValue{code: ErrorCode(values[0].GetInt()), message: encoded.Value, instruction: values[1].GetString(), trace: trace}, nil
+	case "default":
+		return DefaultValue{}, nil
+	}
+	return nil, fmt.Errorf("unknown value type in snapshot: %s", encoded.Type)
+}
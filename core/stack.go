@@ -29,6 +29,18 @@ func (this *Stack[T]) Peek() *T {
 	return &this.top.value
 }
 
+// View the item n places below the top of the stack
+func (this *Stack[T]) PeekAt(n int) *T {
+	if n < 0 || n >= this.length {
+		return nil
+	}
+	curr := this.top
+	for ; n > 0; n-- {
+		curr = curr.prev
+	}
+	return &curr.value
+}
+
 // Pop the top item of the stack and return it
 func (this *Stack[T]) Pop() *T {
 	if this.length == 0 {
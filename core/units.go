@@ -0,0 +1,299 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Exponents of the SI base units m, kg, s, A, K, mol and cd
+	dimension [7]int
+	unit      struct {
+		factor    float64
+		dimension dimension
+	}
+	unitTerm struct {
+		name  string
+		power int
+	}
+	Units []unitTerm
+)
+
+var baseUnitNames = [7]string{"m", "kg", "s", "A", "K", "mol", "cd"}
+
+// Units with their size in SI base units, like the HP-28 UNITS menu
+var unitTable = map[string]unit{
+	// Length
+	"m":   {1, dimension{1}},
+	"km":  {1000, dimension{1}},
+	"cm":  {0.01, dimension{1}},
+	"mm":  {0.001, dimension{1}},
+	"um":  {1e-6, dimension{1}},
+	"nm":  {1e-9, dimension{1}},
+	"in":  {0.0254, dimension{1}},
+	"ft":  {0.3048, dimension{1}},
+	"yd":  {0.9144, dimension{1}},
+	"mi":  {1609.344, dimension{1}},
+	"nmi": {1852, dimension{1}},
+	"au":  {149597870700, dimension{1}},
+	"ly":  {9.4607304725808e15, dimension{1}},
+
+	// Area and volume
+	"ha":   {10000, dimension{2}},
+	"acre": {4046.8564224, dimension{2}},
+	"l":    {0.001, dimension{3}},
+	"ml":   {1e-6, dimension{3}},
+	"gal":  {0.003785411784, dimension{3}},
+	"qt":   {0.000946352946, dimension{3}},
+	"pt":   {0.000473176473, dimension{3}},
+
+	// Mass
+	"kg": {1, dimension{0, 1}},
+	"g":  {0.001, dimension{0, 1}},
+	"mg": {1e-6, dimension{0, 1}},
+	"t":  {1000, dimension{0, 1}},
+	"lb": {0.45359237, dimension{0, 1}},
+	"oz": {0.028349523125, dimension{0, 1}},
+
+	// Time
+	"s":   {1, dimension{0, 0, 1}},
+	"ms":  {0.001, dimension{0, 0, 1}},
+	"us":  {1e-6, dimension{0, 0, 1}},
+	"ns":  {1e-9, dimension{0, 0, 1}},
+	"min": {60, dimension{0, 0, 1}},
+	"h":   {3600, dimension{0, 0, 1}},
+	"d":   {86400, dimension{0, 0, 1}},
+	"yr":  {31556925.9747, dimension{0, 0, 1}},
+	"Hz":  {1, dimension{0, 0, -1}},
+	"kHz": {1e3, dimension{0, 0, -1}},
+	"MHz": {1e6, dimension{0, 0, -1}},
+	"GHz": {1e9, dimension{0, 0, -1}},
+
+	// Speed
+	"mph":  {0.44704, dimension{1, 0, -1}},
+	"kph":  {1 / 3.6, dimension{1, 0, -1}},
+	"knot": {1852.0 / 3600, dimension{1, 0, -1}},
+
+	// Force, energy and power
+	"N":    {1, dimension{1, 1, -2}},
+	"kN":   {1000, dimension{1, 1, -2}},
+	"dyn":  {1e-5, dimension{1, 1, -2}},
+	"lbf":  {4.4482216152605, dimension{1, 1, -2}},
+	"J":    {1, dimension{2, 1, -2}},
+	"kJ":   {1000, dimension{2, 1, -2}},
+	"cal":  {4.1868, dimension{2, 1, -2}},
+	"kcal": {4186.8, dimension{2, 1, -2}},
+	"Btu":  {1055.05585262, dimension{2, 1, -2}},
+	"eV":   {1.602176634e-19, dimension{2, 1, -2}},
+	"Wh":   {3600, dimension{2, 1, -2}},
+	"kWh":  {3.6e6, dimension{2, 1, -2}},
+	"W":    {1, dimension{2, 1, -3}},
+	"kW":   {1000, dimension{2, 1, -3}},
+	"MW":   {1e6, dimension{2, 1, -3}},
+	"hp":   {745.69987158227, dimension{2, 1, -3}},
+
+	// Pressure
+	"Pa":   {1, dimension{-1, 1, -2}},
+	"kPa":  {1000, dimension{-1, 1, -2}},
+	"bar":  {1e5, dimension{-1, 1, -2}},
+	"atm":  {101325, dimension{-1, 1, -2}},
+	"psi":  {6894.75729317, dimension{-1, 1, -2}},
+	"mmHg": {133.322387415, dimension{-1, 1, -2}},
+
+	// Electricity and magnetism
+	"A":    {1, dimension{0, 0, 0, 1}},
+	"mA":   {0.001, dimension{0, 0, 0, 1}},
+	"C":    {1, dimension{0, 0, 1, 1}},
+	"V":    {1, dimension{2, 1, -3, -1}},
+	"mV":   {0.001, dimension{2, 1, -3, -1}},
+	"kV":   {1000, dimension{2, 1, -3, -1}},
+	"ohm":  {1, dimension{2, 1, -3, -2}},
+	"kohm": {1000, dimension{2, 1, -3, -2}},
+	"Mohm": {1e6, dimension{2, 1, -3, -2}},
+	"S":    {1, dimension{-2, -1, 3, 2}},
+	"F":    {1, dimension{-2, -1, 4, 2}},
+	"uF":   {1e-6, dimension{-2, -1, 4, 2}},
+	"nF":   {1e-9, dimension{-2, -1, 4, 2}},
+	"pF":   {1e-12, dimension{-2, -1, 4, 2}},
+	"H":    {1, dimension{2, 1, -2, -2}},
+	"mH":   {0.001, dimension{2, 1, -2, -2}},
+	"uH":   {1e-6, dimension{2, 1, -2, -2}},
+	"T":    {1, dimension{0, 1, -2, -1}},
+	"Wb":   {1, dimension{2, 1, -2, -1}},
+
+	// Temperature differences, amount and luminous intensity
+	"K":   {1, dimension{0, 0, 0, 0, 1}},
+	"R":   {5.0 / 9, dimension{0, 0, 0, 0, 1}},
+	"mol": {1, dimension{0, 0, 0, 0, 0, 1}},
+	"cd":  {1, dimension{0, 0, 0, 0, 0, 0, 1}},
+}
+
+// Parse a unit expression such as kg*m/s^2, where each / divides by the following unit only
+func ParseUnits(input string) (Units, error) {
+	units := Units{}
+	if input == "" {
+		return nil, fmt.Errorf("missing units")
+	}
+	sign := 1
+	start := 0
+	for i := 0; i <= len(input); i++ {
+		if i < len(input) && input[i] != '*' && input[i] != '/' {
+			continue
+		}
+		term := input[start:i]
+		name, exponent, hasExponent := strings.Cut(term, "^")
+		power := 1
+		if hasExponent {
+			p, err := strconv.Atoi(exponent)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exponent: %s", term)
+			}
+			power = p
+		}
+		if name == "1" && !hasExponent && start == 0 {
+			// Allow 1/s
+		} else if _, ok := unitTable[name]; !ok {
+			return nil, fmt.Errorf("unknown unit: %s", name)
+		} else {
+			units = append(units, unitTerm{name: name, power: sign * power})
+		}
+		if i < len(input) && input[i] == '/' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		start = i + 1
+	}
+	return units.simplify(), nil
+}
+
+// Combine repeated units and drop those that cancel out
+func (u Units) simplify() Units {
+	result := Units{}
+	for _, term := range u {
+		found := false
+		for i := range result {
+			if result[i].name == term.name {
+				result[i].power += term.power
+				found = true
+			}
+		}
+		if !found {
+			result = append(result, term)
+		}
+	}
+	simplified := Units{}
+	for _, term := range result {
+		if term.power != 0 {
+			simplified = append(simplified, term)
+		}
+	}
+	return simplified
+}
+
+func (u Units) multiply(other Units, sign int) Units {
+	result := append(Units{}, u...)
+	for _, term := range other {
+		result = append(result, unitTerm{name: term.name, power: sign * term.power})
+	}
+	return result.simplify()
+}
+
+func (u Units) even() bool {
+	for _, term := range u {
+		if term.power%2 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Size of the units in SI base units, and their dimension
+func (u Units) base() (float64, dimension) {
+	factor := 1.0
+	dims := dimension{}
+	for _, term := range u {
+		unit := unitTable[term.name]
+		for i := 0; i < term.power; i++ {
+			factor *= unit.factor
+		}
+		for i := 0; i > term.power; i-- {
+			factor /= unit.factor
+		}
+		for i := range dims {
+			dims[i] += unit.dimension[i] * term.power
+		}
+	}
+	return factor, dims
+}
+
+func (u Units) String() string {
+	numerator := []string{}
+	denominator := []string{}
+	for _, term := range u {
+		power := term.power
+		if power < 0 {
+			power = -power
+		}
+		str := term.name
+		if power != 1 {
+			str += "^" + strconv.Itoa(power)
+		}
+		if term.power > 0 {
+			numerator = append(numerator, str)
+		} else {
+			denominator = append(denominator, str)
+		}
+	}
+	result := strings.Join(numerator, "*")
+	if result == "" {
+		result = "1"
+	}
+	for _, str := range denominator {
+		result += "/" + str
+	}
+	return result
+}
+
+// Express a dimension in SI base units, in the conventional order kg*m/s^2
+func (d dimension) units() Units {
+	units := Units{}
+	for _, i := range []int{1, 0, 2, 3, 4, 5, 6} {
+		if d[i] != 0 {
+			units = append(units, unitTerm{name: baseUnitNames[i], power: d[i]})
+		}
+	}
+	return units
+}
+
+// Create a quantity, or a plain float when the units are dimensionless
+func newQuantity(value float64, units Units) CoreValue {
+	factor, dims := units.base()
+	if dims == (dimension{}) {
+		return FloatValue{value: value * factor}
+	}
+	return QuantityValue{value: value, units: units}
+}
+
+// Convert a quantity to other units of the same dimension
+func (q QuantityValue) convert(units Units) (QuantityValue, bool) {
+	fromFactor, fromDims := q.units.base()
+	toFactor, toDims := units.base()
+	if fromDims != toDims {
+		return QuantityValue{}, false
+	}
+	return QuantityValue{value: q.value * fromFactor / toFactor, units: units}, true
+}
+
+// Get the units named by a string such as 'ft, or carried by a quantity such as 1_ft
+func unitsOf(value CoreValue) (Units, bool) {
+	switch v := value.(type) {
+	case QuantityValue:
+		return v.units, true
+	case StringValue:
+		units, err := ParseUnits(v.value)
+		return units, err == nil
+	}
+	return nil, false
+}
@@ -1,18 +1,21 @@
 package core
 
-var Variables = map[string]CoreValue{
-	// Math
-	"g":     FloatValue{value: 9.80665},
-	"tau":   FloatValue{value: 6.2831855},
-	"pi":    FloatValue{value: 3.1415926},
-	"phi":   FloatValue{value: 1.6180339},
-	"e":     FloatValue{value: 2.7182818},
-	"gauss": FloatValue{value: 0.8346268},
-	"c":     FloatValue{value: 299792458},
+// Constants available to every core, which can be shadowed by variables
+func newConstants() map[string]CoreValue {
+	return map[string]CoreValue{
+		// Math
+		"g":     QuantityValue{value: 9.80665, units: Units{{"m", 1}, {"s", -2}}},
+		"tau":   FloatValue{value: 6.2831855},
+		"pi":    FloatValue{value: 3.1415926},
+		"phi":   FloatValue{value: 1.6180339},
+		"e":     FloatValue{value: 2.7182818},
+		"gauss": FloatValue{value: 0.8346268},
+		"c":     QuantityValue{value: 299792458, units: Units{{"m", 1}, {"s", -1}}},
 
-	// Inernal
-	"ram-bytes":     FloatValue{value: 8192},
-	"render-width":  FloatValue{value: 92},
-	"render-height": FloatValue{value: 30},
-	"render-bytes":  FloatValue{value: 2760},
+		// Inernal
+		"ram-bytes":     FloatValue{value: 8192},
+		"render-width":  FloatValue{value: 92},
+		"render-height": FloatValue{value: 30},
+		"render-bytes":  FloatValue{value: 2760},
+	}
 }
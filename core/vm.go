@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+type (
+	// VM evaluates input synchronously, for embedding 28z in other programs
+	VM struct {
+		core    *Core
+		console []string
+		Output  io.Writer
+	}
+	EvalResult struct {
+		// Stack values, with the top of the stack (x) first
+		Stack   []CoreValue
+		Console []string
+	}
+	EvalError struct {
+		Line        int
+		Input       string
+		Code        ErrorCode
+		Message     string
+		Instruction string
+		// The frames being evaluated when the error was raised, innermost first
+		Trace []string
+	}
+)
+
+// Create a VM with its own core; no tickers run and no channels need to be drained
+func NewVM(rom *Rom) *VM {
+	vm := VM{core: newCore(rom)}
+	vm.core.Console = vm.handleConsole
+	return &vm
+}
+
+func (v *VM) Core() *Core {
+	return v.core
+}
+
+// Limit the resources used by each subsequent evaluation
+func (v *VM) SetQuotas(quotas Quotas) {
+	v.core.Quotas = quotas
+}
+
+// Confine the files read and written by the save and restore instructions to dir
+func (v *VM) SetSnapshotDir(dir string) {
+	v.core.SnapshotDir = dir
+}
+
+// Choose whether sequences run compiled to bytecode, which is the default, or are
+// evaluated directly
+func (v *VM) SetCompiled(compiled bool) {
+	v.core.interpret = !compiled
+}
+
+func (v *VM) Push(value CoreValue) {
+	v.core.Push(value)
+}
+
+// Evaluate newline separated input, stopping at the first line that fails
+func (v *VM) Eval(ctx context.Context, input string) (EvalResult, error) {
+	v.console = []string{}
+	for n, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return v.result(), err
+		}
+		stop := context.AfterFunc(ctx, v.core.Interrupt)
+		v.core.ProcessRaw(line)
+		stop()
+		if v.core.failed() {
+			return v.result(), v.error(ctx, n+1, line)
+		}
+	}
+	return v.result(), nil
+}
+
+// Evaluate a program, such as one loaded with Rom.LoadProgram
+func (v *VM) EvalProgram(ctx context.Context, program SequenceValue) (EvalResult, error) {
+	v.console = []string{}
+	if err := ctx.Err(); err != nil {
+		return v.result(), err
+	}
+	stop := context.AfterFunc(ctx, v.core.Interrupt)
+	v.core.evalTopLevel(func() { v.core.EvalValue(program) })
+	stop()
+	if v.core.failed() {
+		return v.result(), v.error(ctx, 0, "")
+	}
+	return v.result(), nil
+}
+
+// Describe the error raised by the core, wrapping the context error when an
+// evaluation was interrupted by cancellation
+func (v *VM) error(ctx context.Context, line int, input string) error {
+	raised := v.core.Error.(ErrorValue)
+	err := &EvalError{
+		Line:        line,
+		Input:       input,
+		Code:        raised.code,
+		Message:     raised.message,
+		Instruction: raised.instruction,
+		Trace:       raised.trace,
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return err
+}
+
+// Stop the evaluation in progress at the next instruction boundary, from any goroutine
+func (v *VM) Interrupt() {
+	v.core.Interrupt()
+}
+
+func (v *VM) handleConsole(message CommandMessage) {
+	switch message.Command {
+	case Output:
+		v.console = append(v.console, message.Arg)
+		if v.Output != nil {
+			fmt.Fprintln(v.Output, message.Arg)
+		}
+	case Clear:
+		v.console = v.console[:0]
+	case Prompt:
+		Logger.Printf("Ignoring prompt without interactive input: prompt=%s\n", message.Arg)
+	}
+}
+
+func (v *VM) result() EvalResult {
+	return EvalResult{Stack: v.core.GetStackArray(), Console: append([]string{}, v.console...)}
+}
+
+func (e *EvalError) Error() string {
+	if e.Input == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Input, e.Message)
+}
+
+// Get the value at a stack level, where 0 is the top of the stack
+func (r EvalResult) Value(level int) (CoreValue, error) {
+	if level < 0 || level >= len(r.Stack) {
+		return DefaultValue{}, fmt.Errorf("no value at stack level %d", level)
+	}
+	return r.Stack[level], nil
+}
+
+func (r EvalResult) Float(level int) (float64, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return 0, err
+	}
+	if !isNumeric(value) {
+		return 0, fmt.Errorf("stack level %d is not a number: %s", level, value.GetString())
+	}
+	return value.GetFloat(), nil
+}
+
+func (r EvalResult) Int(level int) (int64, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return 0, err
+	}
+	if value.GetType() != IntegerType {
+		return 0, fmt.Errorf("stack level %d is not an integer: %s", level, value.GetString())
+	}
+	return integerOf(value), nil
+}
+
+// Get an integer or rational at a stack level as an exact fraction
+func (r EvalResult) Rational(level int) (*big.Rat, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return nil, err
+	}
+	if value.GetType() != IntegerType && value.GetType() != RationalType {
+		return nil, fmt.Errorf("stack level %d is not a rational: %s", level, value.GetString())
+	}
+	return new(big.Rat).Set(ratOf(value)), nil
+}
+
+func (r EvalResult) Decimal(level int) (*big.Float, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return nil, err
+	}
+	if value.GetType() != DecimalType {
+		return nil, fmt.Errorf("stack level %d is not a decimal: %s", level, value.GetString())
+	}
+	return new(big.Float).Copy(value.(DecimalValue).value), nil
+}
+
+func (r EvalResult) Complex(level int) (complex128, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return 0, err
+	}
+	if !isComplex(value) {
+		return 0, fmt.Errorf("stack level %d is not a number: %s", level, value.GetString())
+	}
+	return complexOf(value), nil
+}
+
+func (r EvalResult) Array(level int) (ArrayValue, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return ArrayValue{}, err
+	}
+	if value.GetType() != ArrayType {
+		return ArrayValue{}, fmt.Errorf("stack level %d is not an array: %s", level, value.GetString())
+	}
+	return value.(ArrayValue), nil
+}
+
+// Get the expression at a stack level, such as x^2+3*x
+func (r EvalResult) Algebraic(level int) (string, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return "", err
+	}
+	if value.GetType() != AlgebraicType {
+		return "", fmt.Errorf("stack level %d is not an expression: %s", level, value.GetString())
+	}
+	return value.(AlgebraicValue).value.String(), nil
+}
+
+func (r EvalResult) Bool(level int) (bool, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return false, err
+	}
+	if value.GetType() != BoolType {
+		return false, fmt.Errorf("stack level %d is not a bool: %s", level, value.GetString())
+	}
+	return value.(BoolValue).value, nil
+}
+
+func (r EvalResult) Map(level int) (MapValue, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return MapValue{}, err
+	}
+	if value.GetType() != MapType {
+		return MapValue{}, fmt.Errorf("stack level %d is not a map: %s", level, value.GetString())
+	}
+	return value.(MapValue), nil
+}
+
+func (r EvalResult) String(level int) (string, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return "", err
+	}
+	if value.GetType() != StringType {
+		return "", fmt.Errorf("stack level %d is not a string: %s", level, value.GetString())
+	}
+	return value.GetString(), nil
+}
+
+func (r EvalResult) Sequence(level int) ([]CoreValue, error) {
+	value, err := r.Value(level)
+	if err != nil {
+		return nil, err
+	}
+	if value.GetType() != SequenceType {
+		return nil, fmt.Errorf("stack level %d is not a sequence: %s", level, value.GetString())
+	}
+	return value.GetSequence(), nil
+}
+
+func NewFloatValue(value float64) CoreValue {
+	return FloatValue{value: value}
+}
+
+func NewIntegerValue(value int64) CoreValue {
+	return IntegerValue{value: value}
+}
+
+func NewComplexValue(value complex128) CoreValue {
+	return ComplexValue{value: value}
+}
+
+func NewBoolValue(value bool) CoreValue {
+	return BoolValue{value: value}
+}
+
+func NewStringValue(value string) CoreValue {
+	return StringValue{value: value}
+}
+
+func NewSequenceValue(values []CoreValue) CoreValue {
+	return SequenceValue{value: values}
+}
@@ -2,10 +2,11 @@ module dmccaffrey/28z
 
 go 1.21
 
+require github.com/mattn/go-tty v0.0.5
+
 require (
 	github.com/mattn/go-colorable v0.1.4 // indirect
 	github.com/mattn/go-isatty v0.0.10 // indirect
 	github.com/mattn/go-runewidth v0.0.7 // indirect
-	github.com/mattn/go-tty v0.0.5 // indirect
 	golang.org/x/sys v0.0.0-20191120155948-bd437916bb0e // indirect
 )
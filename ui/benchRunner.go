@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"context"
+	"dmccaffrey/28z/core"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type (
+	BenchRunner struct {
+		rom      *core.Rom
+		root     string
+		duration time.Duration
+		stdout   io.Writer
+	}
+	benchResult struct {
+		outcome string
+		perOp   time.Duration
+	}
+)
+
+func NewBenchRunner(rom *core.Rom, root string, duration time.Duration) *BenchRunner {
+	return &BenchRunner{rom: rom, root: root, duration: duration, stdout: os.Stdout}
+}
+
+// Time each program below root, or root itself, when evaluated directly and when compiled,
+// returning the number of programs whose results differ between the two
+func (b *BenchRunner) Run() int {
+	mismatched := 0
+	err := filepath.Walk(b.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".28") {
+			return nil
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(path), ".28")
+		program, err := b.rom.LoadProgram(path)
+		if err != nil {
+			fmt.Fprintf(b.stdout, "FAIL %s\n    %s\n", name, err.Error())
+			mismatched++
+			return nil
+		}
+		interpreted := b.measure(program, false)
+		compiled := b.measure(program, true)
+		if interpreted.outcome != compiled.outcome {
+			fmt.Fprintf(b.stdout, "FAIL %s\n    interpreted: %s\n    compiled:    %s\n", name, interpreted.outcome, compiled.outcome)
+			mismatched++
+			return nil
+		}
+		fmt.Fprintf(b.stdout, "%-30s interpreted %12d ns/op    compiled %12d ns/op    %.2fx\n", name,
+			interpreted.perOp.Nanoseconds(), compiled.perOp.Nanoseconds(), float64(interpreted.perOp)/float64(compiled.perOp))
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(b.stdout, "Failed to discover programs: %s\n", err.Error())
+		return 1
+	}
+	return mismatched
+}
+
+// Evaluate a program on fresh VMs until the duration has been spent evaluating it,
+// describing the outcome of the first evaluation
+func (b *BenchRunner) measure(program core.SequenceValue, compiled bool) benchResult {
+	result := benchResult{}
+	elapsed := time.Duration(0)
+	n := 0
+	for ; n == 0 || elapsed < b.duration; n++ {
+		vm := core.NewVM(b.rom)
+		vm.SetCompiled(compiled)
+		start := time.Now()
+		evaluated, err := vm.EvalProgram(context.Background(), program)
+		elapsed += time.Since(start)
+		if n == 0 {
+			result.outcome = describeOutcome(evaluated, err)
+		}
+	}
+	result.perOp = elapsed / time.Duration(n)
+	return result
+}
+
+func describeOutcome(result core.EvalResult, err error) string {
+	values := make([]string, len(result.Stack))
+	for i, value := range result.Stack {
+		values[i] = value.GetString()
+	}
+	outcome := fmt.Sprintf("stack=[%s] console=%d lines", strings.Join(values, " "), len(result.Console))
+	if err != nil {
+		outcome += " error=" + err.Error()
+	}
+	return outcome
+}
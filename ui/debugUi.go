@@ -38,13 +38,9 @@ func (z *Interactive28z) GenerateDebugUi() []byte {
 	}
 
 	bb.WriteString(uiS0)
-	stack := z.core.GetStackArray()
+	stackLines := stackPanelLines(z.core.GetStackArray(), len(stackAliases), z.core)
 	for i := 4; i >= 0; i-- {
-		stackValue := ""
-		if i < len(stack) {
-			stackValue = stack[i].GetString()
-		}
-		stackStr := fmt.Sprintf("%s %1d: %-*s", stackAliases[i], i, 58, stackValue)
+		stackStr := stackLines[i]
 		msgStr := ""
 		regStr := ""
 		switch i {
@@ -53,7 +49,7 @@ func (z *Interactive28z) GenerateDebugUi() []byte {
 			regStr = fmt.Sprintf("%-6s %s", "STATE:", StateToString(z.core.Regs.State))
 			break
 		case 1:
-			msgStr = fmt.Sprintf("%-5s %s", "ERR:", z.message)
+			msgStr = fmt.Sprintf("%-5s %s", "ERR:", z.errorMessage())
 			regStr = fmt.Sprintf("%-6s %03d", "LOOPC:", z.core.Regs.Mode)
 			break
 		case 2:
@@ -70,9 +66,13 @@ func (z *Interactive28z) GenerateDebugUi() []byte {
 		bb.WriteString(fmt.Sprintf(" ║ %-*s ║ %-*.40s ║ %-*.30s ║\n", regWidth, regStr, stackWidth, stackStr, msgWidth, msgStr))
 	}
 	bb.WriteString(uiS3)
-	end := int(math.Min(float64(len(z.console)), float64(scrHeight)))
+	console := z.console
+	if z.paused {
+		console = z.DebugView()
+	}
+	end := int(math.Min(float64(len(console)), float64(scrHeight)))
 	for i := 0; i < end; i++ {
-		bb.WriteString(fmt.Sprintf(" ║%-*.92s║\n", scrWidth, z.console[i]))
+		bb.WriteString(fmt.Sprintf(" ║%-*.92s║\n", scrWidth, console[i]))
 	}
 	for i := scrHeight - end; i > 0; i-- {
 		bb.WriteString(fmt.Sprintf(" ║%-*s║\n", scrWidth, ""))
@@ -93,6 +93,64 @@ func (z *Interactive28z) GenerateDebugUi() []byte {
 	return bb.Bytes()
 }
 
+// Describe the top of the stack in count lines, from the top of the stack down,
+// giving each row of a matrix its own line
+func stackPanelLines(stack []core.CoreValue, count int, display *core.Core) []string {
+	lines := []string{}
+	for level := 0; len(lines) < count; level++ {
+		alias := "   "
+		if level < len(stackAliases) {
+			alias = stackAliases[level]
+		}
+		values := []string{""}
+		if level < len(stack) {
+			values = []string{display.Format(stack[level])}
+			if array, ok := stack[level].(core.ArrayValue); ok && !array.IsVector() {
+				values = display.FormatLines(array)
+			}
+		}
+		for i := len(values) - 1; i >= 0 && len(lines) < count; i-- {
+			label := "      "
+			if i == 0 {
+				label = fmt.Sprintf("%s %1d:", alias, level)
+			}
+			lines = append(lines, fmt.Sprintf("%s %s", label, values[i]))
+		}
+	}
+	return lines
+}
+
+func (z *Interactive28z) errorMessage() string {
+	if z.message == "" && z.core.Error.GetType() != core.DefaultType {
+		return z.core.Error.GetString()
+	}
+	return z.message
+}
+
+// Show the frames, active sequence and every stack while the debugger is paused
+func (z *Interactive28z) DebugView() []string {
+	d := z.core.Debugger
+	lines := []string{" PAUSED: (s)tep, (n)ext, (o)ut, (c)ontinue, (b)reak <name>, clear <name>", "", " Frames:"}
+	for _, line := range d.Where(z.core) {
+		lines = append(lines, "   "+line)
+	}
+	lines = append(lines, "", " Sequence:")
+	for _, line := range d.Listing(z.core, 9) {
+		lines = append(lines, "   "+line)
+	}
+	lines = append(lines, "", " Stacks:")
+	for _, line := range d.Stacks(z.core) {
+		lines = append(lines, "   "+line)
+	}
+	if len(z.debugLines) != 0 {
+		lines = append(lines, "")
+		for _, line := range z.debugLines {
+			lines = append(lines, " "+line)
+		}
+	}
+	return lines
+}
+
 var b2i = map[bool]int8{false: 0, true: 1}
 
 func StateToString(r core.StateRegister) string {
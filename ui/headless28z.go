@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"dmccaffrey/28z/core"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+type Headless28z struct {
+	vm     *core.VM
+	stdout io.Writer
+	stderr io.Writer
+	stdin  *bufio.Scanner
+}
+
+func NewHeadless28z(vm *core.VM) *Headless28z {
+	z := Headless28z{}
+	z.vm = vm
+	z.stdout = os.Stdout
+	z.stderr = os.Stderr
+	z.stdin = bufio.NewScanner(os.Stdin)
+	z.vm.Output = z.stdout
+	return &z
+}
+
+// Pause before the first instruction and read debugger commands from stdin
+func (z *Headless28z) EnableDebugger() {
+	vm := z.vm.Core()
+	vm.Debugger = core.NewDebugger(z.pause)
+	vm.Debugger.Execute(vm, "step")
+}
+
+func (z *Headless28z) pause(vm *core.Core) {
+	for _, line := range vm.Debugger.Where(vm) {
+		fmt.Fprintf(z.stderr, "%s\n", line)
+	}
+	for {
+		fmt.Fprintf(z.stderr, "debug> ")
+		if !z.stdin.Scan() {
+			core.Logger.Printf("Detaching debugger at end of input\n")
+			vm.Debugger.Execute(vm, "continue")
+			vm.Debugger = nil
+			return
+		}
+		resume, lines := vm.Debugger.Execute(vm, z.stdin.Text())
+		for _, line := range lines {
+			fmt.Fprintf(z.stderr, "%s\n", line)
+		}
+		if resume {
+			return
+		}
+	}
+}
+
+// Run a program file with args pushed onto the stack, returning the exit code
+func (z *Headless28z) Run(path string, args []string) int {
+	program, err := z.vm.Core().Env().Rom().LoadProgram(path)
+	if err != nil {
+		fmt.Fprintf(z.stderr, "Failed to load program: %s\n", err.Error())
+		return 2
+	}
+
+	ctx := context.Background()
+	_, err = z.vm.Eval(ctx, strings.Join(args, "\n"))
+	if err != nil {
+		fmt.Fprintf(z.stderr, "Invalid argument: %s\n", err.Error())
+		return 1
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		for range interrupts {
+			z.vm.Interrupt()
+		}
+	}()
+
+	core.Logger.Printf("Evaluating program: path=%s\n", path)
+	result, err := z.vm.EvalProgram(ctx, program)
+	for i := len(result.Stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(z.stdout, "%d: %s\n", i, z.vm.Core().Format(result.Stack[i]))
+	}
+	if err != nil {
+		fmt.Fprintf(z.stderr, "Error: %s\n", err.Error())
+		evalErr := &core.EvalError{}
+		if errors.As(err, &evalErr) {
+			for _, frame := range evalErr.Trace {
+				fmt.Fprintf(z.stderr, "  at %s\n", frame)
+			}
+		}
+		return 1
+	}
+	return 0
+}
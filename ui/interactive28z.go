@@ -2,6 +2,8 @@ package ui
 
 import (
 	"dmccaffrey/28z/core"
+	"os"
+	"os/signal"
 	"strings"
 	"time"
 
@@ -16,10 +18,14 @@ type Interactive28z struct {
 	message      string
 	console      []string
 	runes        []rune
-	ticker       time.Ticker
+	ticker       *time.Ticker
 	input        chan rune
 	lastUiUpdate time.Time
 	run          bool
+	paused       bool
+	debugLines   []string
+	resume       chan bool
+	interrupts   chan os.Signal
 }
 
 func NewInteractive28z(vm *core.Core) *Interactive28z {
@@ -35,12 +41,33 @@ func NewInteractive28z(vm *core.Core) *Interactive28z {
 	z.message = ""
 	z.prompt = ""
 	z.runes = make([]rune, 0, 128)
-	z.ticker = *time.NewTicker(1 * time.Second)
+	z.ticker = time.NewTicker(1 * time.Second)
 	z.input = make(chan rune)
 	z.run = true
+	z.resume = make(chan bool)
+	z.interrupts = make(chan os.Signal, 1)
+	vm.Debugger = core.NewDebugger(z.pause)
+	for _, d := range vm.Env().Rom().Diagnostics {
+		z.Output("ROM error: " + d.Error())
+	}
 	return &z
 }
 
+// Block the core until a resuming debugger command is entered
+func (z *Interactive28z) pause(vm *core.Core) {
+	vm.Emit(core.Paused, "")
+	<-z.resume
+}
+
+func (z *Interactive28z) debugCommand(input string) {
+	resume, lines := z.core.Debugger.Execute(z.core, input)
+	z.debugLines = lines
+	if resume && z.paused {
+		z.paused = false
+		z.resume <- true
+	}
+}
+
 func (z *Interactive28z) Display() {
 	z.tty.Output().Write(z.GenerateDebugUi())
 	z.lastUiUpdate = time.Now()
@@ -61,6 +88,8 @@ func (z *Interactive28z) Clear() {
 
 func (z *Interactive28z) Run() {
 	defer z.tty.Close()
+	signal.Notify(z.interrupts, os.Interrupt)
+	defer signal.Stop(z.interrupts)
 	z.Display()
 	go z.PollRune()
 	z.HandleEvents()
@@ -82,6 +111,10 @@ func (z *Interactive28z) HandleEvents() {
 				z.Output(message.Arg)
 			case core.StateUpdated:
 				z.Display()
+			case core.Paused:
+				z.paused = true
+				z.debugLines = nil
+				z.Display()
 			}
 		case r := <-z.input:
 			if !z.HandleRune(r) {
@@ -89,12 +122,24 @@ func (z *Interactive28z) HandleEvents() {
 				return
 			}
 			z.Display()
+		case <-z.interrupts:
+			z.Interrupt()
+			z.Display()
 		case <-z.ticker.C:
 			z.Display()
 		}
 	}
 }
 
+// Stop the program being evaluated, releasing the debugger if it is paused
+func (z *Interactive28z) Interrupt() {
+	core.Logger.Println("Interrupting evaluation from the keyboard")
+	z.core.Interrupt()
+	if z.paused {
+		z.debugCommand("continue")
+	}
+}
+
 func (z *Interactive28z) exit() {
 	z.run = false
 	z.core.Halt()
@@ -138,6 +183,10 @@ func (z *Interactive28z) HandleRune(r rune) bool {
 			return false
 		}
 		z.lastInput = input
+		if z.paused || strings.HasPrefix(input, ":") {
+			z.debugCommand(strings.TrimPrefix(input, ":"))
+			return true
+		}
 		z.core.Input <- input
 		z.prompt = ""
 	default:
@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"context"
+	"dmccaffrey/28z/core"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const expectPrefix = "# expect "
+
+type (
+	TestRunner struct {
+		rom    *core.Rom
+		root   string
+		stdout io.Writer
+	}
+	testExpectations struct {
+		stack   []string
+		console []string
+		ram     map[int][]byte
+		error   string
+		count   int
+	}
+	testOutcome struct {
+		stack   []string
+		console []string
+		ram     []byte
+		error   string
+	}
+)
+
+func NewTestRunner(rom *core.Rom, root string) *TestRunner {
+	return &TestRunner{rom: rom, root: root, stdout: os.Stdout}
+}
+
+// Run all test programs below root, returning the number of failures
+func (t *TestRunner) Run() int {
+	passed, failed, skipped := 0, 0, 0
+	err := filepath.Walk(t.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".28") {
+			return nil
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(path), ".28")
+		expected, err := parseExpectations(path)
+		if err != nil {
+			fmt.Fprintf(t.stdout, "FAIL %s\n    %s\n", name, err.Error())
+			failed++
+			return nil
+		}
+		if expected.count == 0 {
+			fmt.Fprintf(t.stdout, "SKIP %s (no expectations)\n", name)
+			skipped++
+			return nil
+		}
+		diffs, err := t.runTest(path, expected)
+		if err != nil {
+			diffs = append(diffs, err.Error())
+		}
+		if len(diffs) != 0 {
+			fmt.Fprintf(t.stdout, "FAIL %s\n", name)
+			for _, diff := range diffs {
+				fmt.Fprintf(t.stdout, "    %s\n", diff)
+			}
+			failed++
+			return nil
+		}
+		fmt.Fprintf(t.stdout, "PASS %s\n", name)
+		passed++
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(t.stdout, "Failed to discover tests: %s\n", err.Error())
+		return 1
+	}
+	fmt.Fprintf(t.stdout, "\n%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	return failed
+}
+
+func parseExpectations(path string) (testExpectations, error) {
+	expected := testExpectations{ram: map[int][]byte{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return expected, err
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(line, expectPrefix) {
+			continue
+		}
+		kind, value, ok := strings.Cut(strings.TrimPrefix(line, expectPrefix), ": ")
+		if !ok {
+			kind, value = strings.TrimSuffix(strings.TrimPrefix(line, expectPrefix), ":"), ""
+		}
+		expected.count++
+		switch {
+		case kind == "stack" && value == "":
+			expected.stack = []string{}
+		case kind == "stack":
+			expected.stack = append(expected.stack, core.RawToImmediateCoreValue(value).GetString())
+		case kind == "console":
+			expected.console = append(expected.console, value)
+		case kind == "error":
+			expected.error = value
+		case strings.HasPrefix(kind, "ram "):
+			offset, err := strconv.Atoi(strings.TrimPrefix(kind, "ram "))
+			if err != nil {
+				return expected, fmt.Errorf("line %d: invalid RAM offset: %s", n+1, kind)
+			}
+			for _, field := range strings.Fields(value) {
+				b, err := strconv.ParseUint(field, 0, 8)
+				if err != nil {
+					return expected, fmt.Errorf("line %d: invalid RAM byte: %s", n+1, field)
+				}
+				expected.ram[offset] = append(expected.ram[offset], byte(b))
+			}
+		default:
+			return expected, fmt.Errorf("line %d: unknown expectation: %s", n+1, kind)
+		}
+	}
+	return expected, nil
+}
+
+func (t *TestRunner) runTest(path string, expected testExpectations) ([]string, error) {
+	program, err := t.rom.LoadProgram(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := core.NewVM(t.rom)
+	result, err := vm.EvalProgram(context.Background(), program)
+
+	actual := testOutcome{console: result.Console, ram: vm.Core().Ram}
+	for i := len(result.Stack) - 1; i >= 0; i-- {
+		actual.stack = append(actual.stack, result.Stack[i].GetString())
+	}
+	if err != nil {
+		actual.error = err.Error()
+	}
+	return compareOutcome(expected, actual), nil
+}
+
+func compareOutcome(expected testExpectations, actual testOutcome) []string {
+	diffs := []string{}
+	if expected.error != actual.error {
+		diffs = append(diffs, "error:", "- "+expected.error, "+ "+actual.error)
+	}
+	if expected.stack != nil {
+		diffs = append(diffs, diffLines("stack", expected.stack, actual.stack)...)
+	}
+	if expected.console != nil {
+		diffs = append(diffs, diffLines("console", expected.console, actual.console)...)
+	}
+	for offset, bytes := range expected.ram {
+		end := offset + len(bytes)
+		if offset < 0 || end > len(actual.ram) {
+			diffs = append(diffs, fmt.Sprintf("ram %d: out of range", offset))
+			continue
+		}
+		if string(bytes) != string(actual.ram[offset:end]) {
+			diffs = append(diffs, fmt.Sprintf("ram %d:", offset),
+				fmt.Sprintf("- %v", bytes), fmt.Sprintf("+ %v", actual.ram[offset:end]))
+		}
+	}
+	return diffs
+}
+
+// Produce a line by line diff, marking missing lines with - and unexpected lines with +
+func diffLines(label string, expected []string, actual []string) []string {
+	diffs := []string{}
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			diffs = append(diffs, fmt.Sprintf("- %d: %s", i, expected[i]))
+		case i >= len(expected):
+			diffs = append(diffs, fmt.Sprintf("+ %d: %s", i, actual[i]))
+		case expected[i] != actual[i]:
+			diffs = append(diffs, fmt.Sprintf("- %d: %s", i, expected[i]), fmt.Sprintf("+ %d: %s", i, actual[i]))
+		}
+	}
+	if len(diffs) == 0 {
+		return diffs
+	}
+	return append([]string{label + ":"}, diffs...)
+}